@@ -0,0 +1,215 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"inet.af/netaddr"
+)
+
+// ErrExhausted is returned when a prefix or range has no free ip address
+var ErrExhausted = errors.New("no free ip address")
+
+// AllocateIP allocates the first free ip address of the prefix or range
+// from and stores value for it. Addresses that belong to more specific
+// prefixes or ranges are not allocated from a parent and for an ipv4
+// prefix the network and broadcast address are skipped
+func (ipam *IpTree[T]) AllocateIP(from string, value T) (_ string, err error) {
+	defer ipam.metrics.observe(opAllocate, &err)
+	defer ipam.metrics.observeAllocation(time.Now())
+	free, err := ipam.freeIPs(from)
+	if err != nil {
+		return "", err
+	}
+	ranges := free.Ranges()
+	if len(ranges) == 0 {
+		return "", errors.Wrapf(ErrExhausted, "%s", from)
+	}
+	ip := ranges[0].From()
+	if err := ipam.addValue(netaddr.IPPrefixFrom(ip, ip.BitLen()), ip.String(), value, (*Metadata).SetIpAddress); err != nil {
+		return "", errors.Wrap(err, "error adding ip address")
+	}
+	var old T
+	if err := ipam.emit(EventAllocated, ip.String(), old, value); err != nil {
+		return "", err
+	}
+	return ip.String(), nil
+}
+
+// ClaimIP allocates the ip address and stores value for it, the address
+// should be part of a prefix or range and not be allocated already
+func (ipam *IpTree[T]) ClaimIP(ip string, value T) error {
+	addr, err := netaddr.ParseIP(ip)
+	if err != nil {
+		err = errors.Wrapf(ErrInvalid, "cannot parse ip address %s: %v", ip, err)
+		ipam.metrics.observe(opClaim, &err)
+		return err
+	}
+	return ipam.ClaimAddr(addr, value)
+}
+
+// ClaimAddr allocates the ip address like ClaimIP
+func (ipam *IpTree[T]) ClaimAddr(addr netaddr.IP, value T) (err error) {
+	defer ipam.metrics.observe(opClaim, &err)
+	defer ipam.metrics.observeAllocation(time.Now())
+	if addr.IsZero() {
+		return errors.Wrap(ErrInvalid, "ip address is not set")
+	}
+	addr = addr.Unmap()
+	ip := addr.String()
+	if _, ok := ipam.GetAddr(addr); ok {
+		return errors.Wrapf(ErrOverlap, "ip address %s is allocated", ip)
+	}
+	if !ipam.holds(addr) {
+		return errors.Wrapf(ErrNoParent, "ip address %s", ip)
+	}
+	if err := ipam.addValue(netaddr.IPPrefixFrom(addr, addr.BitLen()), ip, value, (*Metadata).SetIpAddress); err != nil {
+		return errors.Wrap(err, "error adding ip address")
+	}
+	var old T
+	return ipam.emit(EventAllocated, ip, old, value)
+}
+
+// holds returns true when a prefix, range or ip address of the tree holds
+// the ip address
+func (ipam *IpTree[T]) holds(addr netaddr.IP) bool {
+	if _, _, ok := ipam.GetTree(familyOfIP(addr)).Match(addr); ok {
+		return true
+	}
+	found := false
+	ipam.rangesOverlapping(netaddr.IPRangeFrom(addr, addr), func(*rangeNode[T]) bool {
+		found = true
+		return false
+	})
+	return found
+}
+
+// ReleaseIP releases an allocated ip address
+func (ipam *IpTree[T]) ReleaseIP(ip string) (err error) {
+	defer ipam.metrics.observe(opRelease, &err)
+	ip, _, err = ipam.tree(ip)
+	if err != nil {
+		return err
+	}
+	if _, ok := ipam.GetIP(ip); !ok {
+		return errors.Wrapf(ErrNotFound, "ip address %s", ip)
+	}
+	return ipam.deleteEntry(ip)
+}
+
+// GetIP returns the value stored for the allocated ip address
+func (ipam *IpTree[T]) GetIP(ip string) (T, bool) {
+	var value T
+	addr, err := netaddr.ParseIP(ip)
+	if err != nil {
+		return value, false
+	}
+	return ipam.GetAddr(addr)
+}
+
+// GetAddr returns the value stored for the allocated ip address
+func (ipam *IpTree[T]) GetAddr(addr netaddr.IP) (T, bool) {
+	addr = addr.Unmap()
+	return ipam.getValue(addr.String(), netaddr.IPPrefixFrom(addr, addr.BitLen()))
+}
+
+// Allocations returns the ip addresses allocated from the prefix or range
+func (ipam *IpTree[T]) Allocations(from string) ([]Entry[T], error) {
+	r, cover, err := ipam.parentRange(from)
+	if err != nil {
+		return nil, err
+	}
+	entries := []Entry[T]{}
+	ipam.GetTree(familyOfIP(cover.IP())).WalkPrefix(cover, func(_ netaddr.IPPrefix, v interface{}) bool {
+		if d, ok := ipam.getData(v); ok && d.GetMeta().HasIpAddress() {
+			for k, value := range d.GetValue() {
+				if ip, err := netaddr.ParseIP(k); err == nil && r.Contains(ip) {
+					rev, _ := ipam.EntryRevision(k)
+					entries = append(entries, Entry[T]{Key: k, Value: value, Revision: rev})
+				}
+			}
+		}
+		return true
+	})
+	return entries, nil
+}
+
+// parentRange returns the addresses of the prefix or range from that
+// exists in the tree together with a prefix covering it
+func (ipam *IpTree[T]) parentRange(from string) (netaddr.IPRange, netaddr.IPPrefix, error) {
+	from, _, err := ipam.tree(from)
+	if err != nil {
+		return netaddr.IPRange{}, netaddr.IPPrefix{}, err
+	}
+	if strings.Contains(from, "-") {
+		if _, ok := ipam.GetRange(from); !ok {
+			return netaddr.IPRange{}, netaddr.IPPrefix{}, errors.Wrapf(ErrNotFound, "range %s", from)
+		}
+		r, err := netaddr.ParseIPRange(from)
+		if err != nil {
+			return netaddr.IPRange{}, netaddr.IPPrefix{}, errors.Wrap(err, "cannot parse range")
+		}
+		cover, err := CoveringPrefix(r)
+		return r, cover, err
+	}
+	if _, ok := ipam.GetPrefix(from); !ok {
+		return netaddr.IPRange{}, netaddr.IPPrefix{}, errors.Wrapf(ErrNotFound, "prefix %s", from)
+	}
+	p, err := netaddr.ParseIPPrefix(from)
+	if err != nil {
+		return netaddr.IPRange{}, netaddr.IPPrefix{}, errors.Wrap(err, "cannot parse prefix")
+	}
+	return p.Range(), p, nil
+}
+
+// freeIPs returns the addresses of the prefix or range from that can be
+// allocated
+func (ipam *IpTree[T]) freeIPs(from string) (*netaddr.IPSet, error) {
+	from, _, err := ipam.tree(from)
+	if err != nil {
+		return nil, err
+	}
+	r, cover, err := ipam.parentRange(from)
+	if err != nil {
+		return nil, err
+	}
+	var b netaddr.IPSetBuilder
+	b.AddRange(r)
+	if !strings.Contains(from, "-") && cover.IP().Is4() && cover.Bits() < 31 {
+		// skip the network and broadcast address
+		b.Remove(r.From())
+		b.Remove(r.To())
+	}
+	// the addresses of more specific entries and allocated addresses are
+	// not free
+	ipam.GetTree(familyOfIP(cover.IP())).WalkPrefix(cover, func(_ netaddr.IPPrefix, v interface{}) bool {
+		d, ok := ipam.getData(v)
+		if !ok {
+			return true
+		}
+		for k := range d.GetValue() {
+			if k == from {
+				continue
+			}
+			switch {
+			case strings.Contains(k, "/"):
+				if o, err := netaddr.ParseIPPrefix(k); err == nil && rangeContains(r, o.Range()) && o.Range() != r {
+					b.RemovePrefix(o)
+				}
+			default:
+				if ip, err := netaddr.ParseIP(k); err == nil {
+					b.Remove(ip)
+				}
+			}
+		}
+		return true
+	})
+	ipam.rangesOverlapping(r, func(n *rangeNode[T]) bool {
+		if n.key != from && rangeContains(r, n.r) {
+			b.RemoveRange(n.r)
+		}
+		return true
+	})
+	return b.IPSet()
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"inet.af/netaddr"
+)
+
+// AuditRecord is a change of the tree together with who made it and why
+type AuditRecord[T any] struct {
+	Revision uint64    `json:"revision"`
+	Time     time.Time `json:"time"`
+	Actor    string    `json:"actor,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+	Type     EventType `json:"type"`
+	Key      string    `json:"key"`
+	Before   T         `json:"before"`
+	After    T         `json:"after"`
+}
+
+// AuditQuery selects audit records, empty fields select all records
+type AuditQuery struct {
+	// Prefix only selects records of entries that overlap with this prefix
+	Prefix string
+	// Actor only selects records of this actor
+	Actor string
+	// FromRevision only selects records after this revision
+	FromRevision uint64
+}
+
+// AuditSink is an append-only store of audit records
+type AuditSink[T any] interface {
+	// Append stores a record, records are appended in revision order
+	Append(r AuditRecord[T]) error
+	// Query returns the records that match the query in revision order
+	Query(q AuditQuery) ([]AuditRecord[T], error)
+}
+
+// SetAuditSink records every change of the tree into the sink s
+func (ipam *IpTree[T]) SetAuditSink(s AuditSink[T]) {
+	if ipam.events == nil {
+		return
+	}
+	ipam.events.m.Lock()
+	defer ipam.events.m.Unlock()
+	ipam.events.audit = s
+}
+
+// As returns a view of the tree that records actor and reason in the audit
+// log for the changes made through it, the view shares the tree
+func (ipam *IpTree[T]) As(actor, reason string) *IpTree[T] {
+	view := *ipam
+	view.actor = actor
+	view.reason = reason
+	return &view
+}
+
+// Audit returns the audit records of the tree that match the query
+func (ipam *IpTree[T]) Audit(q AuditQuery) ([]AuditRecord[T], error) {
+	if ipam.events == nil {
+		return nil, errors.New("tree has no audit sink")
+	}
+	ipam.events.m.Lock()
+	s := ipam.events.audit
+	ipam.events.m.Unlock()
+	if s == nil {
+		return nil, errors.New("tree has no audit sink")
+	}
+	return s.Query(q)
+}
+
+// audit appends the record of event e to the audit sink, it is called
+// with the lock of the event log held such that records are appended in
+// revision order
+func (ipam *IpTree[T]) audit(e Event[T]) error {
+	s := ipam.events.audit
+	if s == nil {
+		return nil
+	}
+	r := AuditRecord[T]{
+		Revision: e.Revision,
+		Time:     e.Time,
+		Actor:    ipam.actor,
+		Reason:   ipam.reason,
+		Type:     e.Type,
+		Key:      e.Key,
+		Before:   e.OldValue,
+		After:    e.NewValue,
+	}
+	if err := s.Append(r); err != nil {
+		return errors.Wrapf(err, "cannot append audit record of revision %d", r.Revision)
+	}
+	return nil
+}
+
+// revert undoes a change of type t of the entry with key k that could not
+// be audited, an updated or deleted entry gets back its old value and an
+// added entry is removed. The change is not emitted
+func (ipam *IpTree[T]) revert(t EventType, k string, old T) error {
+	restore := t != EventAdded && t != EventAllocated
+	switch {
+	case strings.Contains(k, "-"):
+		r, err := ParseRange(k)
+		if err != nil {
+			return err
+		}
+		x := ipam.rangeIndex(familyOfIP(r.From()))
+		if restore {
+			x.insert(r, k, old)
+		} else {
+			x.delete(r)
+		}
+		return nil
+	case strings.Contains(k, "/"):
+		p, err := ParsePrefix(k)
+		if err != nil {
+			return err
+		}
+		if restore {
+			return ipam.addValue(p, k, old, (*Metadata).SetIpPrefix)
+		}
+		return ipam.deleteValue(p, k)
+	default:
+		ip, err := netaddr.ParseIP(k)
+		if err != nil {
+			return errors.Wrap(err, "cannot parse ip address")
+		}
+		p := netaddr.IPPrefixFrom(ip, ip.BitLen())
+		if restore {
+			return ipam.addValue(p, k, old, (*Metadata).SetIpAddress)
+		}
+		return ipam.deleteValue(p, k)
+	}
+}
+
+// matchAudit returns true if the record r is selected by the query q
+func matchAudit[T any](q AuditQuery, r AuditRecord[T]) (bool, error) {
+	if r.Revision <= q.FromRevision {
+		return false, nil
+	}
+	if q.Actor != "" && r.Actor != q.Actor {
+		return false, nil
+	}
+	if q.Prefix != "" {
+		p, _, err := Canonicalize(q.Prefix)
+		if err != nil {
+			return false, err
+		}
+		pfx, err := netaddr.ParseIPPrefix(p)
+		if err != nil {
+			return false, errors.Wrapf(ErrInvalid, "cannot parse prefix %s: %v", p, err)
+		}
+		kr, err := keyRange(r.Key)
+		if err != nil || !kr.Overlaps(pfx.Range()) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// FileAuditSink appends audit records as json lines to a file
+type FileAuditSink[T any] struct {
+	m    sync.Mutex
+	path string
+	f    *os.File
+}
+
+// NewFileAuditSink opens the audit file at path, records are appended to
+// the existing records of the file
+func NewFileAuditSink[T any](path string) (*FileAuditSink[T], error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot open audit file")
+	}
+	return &FileAuditSink[T]{
+		path: path,
+		f:    f,
+	}, nil
+}
+
+// Append writes the record to the file and syncs it to disk
+func (s *FileAuditSink[T]) Append(r AuditRecord[T]) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal audit record")
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	if _, err := s.f.Write(append(b, '\n')); err != nil {
+		return errors.Wrap(err, "cannot write audit record")
+	}
+	return s.f.Sync()
+}
+
+// Query reads the records of the file that match the query
+func (s *FileAuditSink[T]) Query(q AuditQuery) ([]AuditRecord[T], error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot open audit file")
+	}
+	defer f.Close()
+
+	records := []AuditRecord[T]{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var r AuditRecord[T]
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, errors.Wrap(err, "cannot unmarshal audit record")
+		}
+		ok, err := matchAudit(q, r)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			records = append(records, r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "cannot read audit file")
+	}
+	return records, nil
+}
+
+// Close closes the audit file
+func (s *FileAuditSink[T]) Close() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.f.Close()
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var errSinkFailed = errors.New("sink failed")
+
+// failingSink rejects every record once fail is set
+type failingSink struct {
+	fail    bool
+	records []AuditRecord[string]
+}
+
+func (s *failingSink) Append(r AuditRecord[string]) error {
+	if s.fail {
+		return errSinkFailed
+	}
+	s.records = append(s.records, r)
+	return nil
+}
+
+func (s *failingSink) Query(q AuditQuery) ([]AuditRecord[string], error) {
+	return s.records, nil
+}
+
+// a change that cannot be audited fails and leaves the tree, its revision
+// and its leases as they were
+func TestAuditFailureRevertsChange(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*IpTree[string]) error
+	}{
+		{"add prefix", func(ipam *IpTree[string]) error { return ipam.AddPrefix("10.1.0.0/16", "new") }},
+		{"update prefix", func(ipam *IpTree[string]) error { return ipam.AddPrefix("10.0.0.0/16", "new") }},
+		{"add range", func(ipam *IpTree[string]) error { return ipam.AddRange("10.0.2.0-10.0.2.9", "new") }},
+		{"delete range", func(ipam *IpTree[string]) error { return ipam.DeleteRange("10.0.0.10-10.0.0.20") }},
+		{"claim", func(ipam *IpTree[string]) error { return ipam.ClaimIP("10.0.1.6", "new") }},
+		{"release", func(ipam *IpTree[string]) error { return ipam.ReleaseIP("10.0.1.7") }},
+		{"cascade", func(ipam *IpTree[string]) error {
+			_, err := ipam.DeletePrefixWithOptions("10.0.0.0/16", DeleteOptions{Mode: DeleteCascade})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ipam := deleteTree(t)
+			if err := ipam.ClaimIPWithLease("10.0.1.7", "leased", "owner", time.Hour); err != nil {
+				t.Fatal(err)
+			}
+			s := &failingSink{fail: true}
+			ipam.SetAuditSink(s)
+			keys, rev, leases := exportKeys(ipam), ipam.Revision(), ipam.Leases()
+
+			if err := tt.change(ipam); errors.Cause(err) != errSinkFailed {
+				t.Fatalf("change returned %v, want %v", err, errSinkFailed)
+			}
+			if got := exportKeys(ipam); !reflect.DeepEqual(got, keys) {
+				t.Errorf("entries %v, want %v", got, keys)
+			}
+			if v, _ := ipam.GetPrefix("10.0.0.0/16"); v != "10.0.0.0/16" {
+				t.Errorf("prefix has value %q", v)
+			}
+			if got := ipam.Revision(); got != rev {
+				t.Errorf("revision %d, want %d", got, rev)
+			}
+			if got := ipam.Leases(); !reflect.DeepEqual(got, leases) {
+				t.Errorf("leases %v, want %v", got, leases)
+			}
+			if err := ipam.CheckInvariants(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net"
+
+	"github.com/k-sone/critbitgo"
+	"inet.af/netaddr"
+)
+
+// Backend is the tree of an address family that holds the nodes of the
+// ipam, a node is a prefix with the data of the prefixes, ranges and ip
+// addresses stored in it. The walks visit the nodes in address order, a
+// node goes before the more specific nodes it holds
+type Backend interface {
+	// Get returns the value of the node of prefix p
+	Get(p netaddr.IPPrefix) (interface{}, bool)
+	// Add stores the value of the node of prefix p, the value of an
+	// existing node is replaced
+	Add(p netaddr.IPPrefix, v interface{}) error
+	// Delete removes the node of prefix p and returns its value
+	Delete(p netaddr.IPPrefix) (interface{}, bool)
+	// Match returns the most specific node that holds the ip address
+	Match(ip netaddr.IP) (netaddr.IPPrefix, interface{}, bool)
+	// WalkMatch calls fn for the nodes that hold prefix p, including the
+	// node of p itself, the walk stops when fn returns false
+	WalkMatch(p netaddr.IPPrefix, fn func(netaddr.IPPrefix, interface{}) bool)
+	// WalkPrefix calls fn for the nodes that are equal to or more specific
+	// than prefix p, the walk stops when fn returns false
+	WalkPrefix(p netaddr.IPPrefix, fn func(netaddr.IPPrefix, interface{}) bool)
+	// Walk calls fn for all nodes, the walk stops when fn returns false
+	Walk(fn func(netaddr.IPPrefix, interface{}) bool)
+	// Size returns the amount of nodes
+	Size() int
+	// Clear removes all nodes
+	Clear()
+}
+
+// WithBackend sets the constructor of the backend of every address family,
+// the critbit backend is used when it is not set
+func WithBackend(newBackend func() Backend) Option {
+	return func(o *options) {
+		o.backend = newBackend
+	}
+}
+
+// newBackends returns an empty backend for every address family
+func newBackends(o options) map[Family]Backend {
+	newBackend := o.backend
+	if newBackend == nil {
+		newBackend = NewCritbitBackend
+	}
+	t := map[Family]Backend{}
+	for _, f := range Families {
+		t[f] = newBackend()
+	}
+	return t
+}
+
+// critbitBackend is a backend on a critbitgo.Net
+type critbitBackend struct {
+	n *critbitgo.Net
+}
+
+// NewCritbitBackend returns a backend on a critbit tree
+func NewCritbitBackend() Backend {
+	return &critbitBackend{n: critbitgo.NewNet()}
+}
+
+func (b *critbitBackend) Get(p netaddr.IPPrefix) (interface{}, bool) {
+	v, ok, err := b.n.Get(p.IPNet())
+	return v, ok && err == nil
+}
+
+func (b *critbitBackend) Add(p netaddr.IPPrefix, v interface{}) error {
+	return b.n.Add(p.IPNet(), v)
+}
+
+func (b *critbitBackend) Delete(p netaddr.IPPrefix) (interface{}, bool) {
+	v, ok, err := b.n.Delete(p.IPNet())
+	return v, ok && err == nil
+}
+
+func (b *critbitBackend) Match(ip netaddr.IP) (netaddr.IPPrefix, interface{}, bool) {
+	route, v, err := b.n.MatchIP(ip.IPAddr().IP)
+	if err != nil || route == nil {
+		return netaddr.IPPrefix{}, nil, false
+	}
+	pfx, _ := netaddr.FromStdIPNet(route)
+	return pfx, v, true
+}
+
+func (b *critbitBackend) WalkMatch(p netaddr.IPPrefix, fn func(netaddr.IPPrefix, interface{}) bool) {
+	b.n.WalkMatch(p.IPNet(), critbitHandler(fn))
+}
+
+// WalkPrefix walks the byte aligned prefix and filters the result as
+// critbitgo WalkPrefix aborts on the first node that does not match a
+// prefix that is not byte aligned
+func (b *critbitBackend) WalkPrefix(p netaddr.IPPrefix, fn func(netaddr.IPPrefix, interface{}) bool) {
+	aligned := netaddr.IPPrefixFrom(p.IP(), p.Bits()/8*8).Masked()
+	b.n.WalkPrefix(aligned.IPNet(), func(n *net.IPNet, v interface{}) bool {
+		pfx, ok := netaddr.FromStdIPNet(n)
+		if !ok || pfx.Bits() < p.Bits() || !p.Contains(pfx.IP()) {
+			return true
+		}
+		return fn(pfx, v)
+	})
+}
+
+func (b *critbitBackend) Walk(fn func(netaddr.IPPrefix, interface{}) bool) {
+	b.n.Walk(nil, critbitHandler(fn))
+}
+
+func (b *critbitBackend) Size() int {
+	return b.n.Size()
+}
+
+func (b *critbitBackend) Clear() {
+	b.n.Clear()
+}
+
+func critbitHandler(fn func(netaddr.IPPrefix, interface{}) bool) func(*net.IPNet, interface{}) bool {
+	return func(n *net.IPNet, v interface{}) bool {
+		pfx, _ := netaddr.FromStdIPNet(n)
+		return fn(pfx, v)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/pkg/errors"
+	"inet.af/netaddr"
+)
+
+// TestBackendConformance applies a random sequence of adds and deletes of
+// prefixes to every backend that ships with the ipam and compares every
+// operation of the backend with a reference model after every step
+func TestBackendConformance(t *testing.T) {
+	backends := []struct {
+		name string
+		new  func() Backend
+	}{
+		{"critbit", NewCritbitBackend},
+		{"radix", NewRadixBackend},
+	}
+	steps := 500
+	if testing.Short() {
+		steps = 100
+	}
+	for _, bk := range backends {
+		for _, f := range Families {
+			for seed := int64(1); seed <= 2; seed++ {
+				t.Run(fmt.Sprintf("%s/%s/%d", bk.name, f, seed), func(t *testing.T) {
+					if err := checkBackend(bk.new(), f, seed, steps); err != nil {
+						t.Fatal(err)
+					}
+				})
+			}
+		}
+	}
+}
+
+// checkBackend runs the conformance steps of family f on backend b, the
+// first difference with the reference model is returned
+func checkBackend(b Backend, f Family, seed int64, steps int) error {
+	r := rand.New(rand.NewSource(seed))
+	model := map[netaddr.IPPrefix]interface{}{}
+	for i := 0; i < steps; i++ {
+		p := randomPrefix(r, f)
+		if r.Intn(3) == 0 {
+			v, ok := b.Delete(p)
+			mv, mok := model[p]
+			delete(model, p)
+			if ok != mok || v != mv {
+				return errors.Errorf("step %d: delete %s returned %v %t, expected %v %t", i, p, v, ok, mv, mok)
+			}
+		} else {
+			if err := b.Add(p, i); err != nil {
+				return errors.Wrapf(err, "step %d: add %s", i, p)
+			}
+			model[p] = i
+		}
+		if err := compareBackend(b, model, r, f); err != nil {
+			return errors.Wrapf(err, "step %d", i)
+		}
+	}
+	b.Clear()
+	if b.Size() != 0 {
+		return errors.Errorf("size %d after clear", b.Size())
+	}
+	return nil
+}
+
+// compareBackend compares the backend with the reference model
+func compareBackend(b Backend, model map[netaddr.IPPrefix]interface{}, r *rand.Rand, f Family) error {
+	if b.Size() != len(model) {
+		return errors.Errorf("size %d, expected %d", b.Size(), len(model))
+	}
+	all := make([]netaddr.IPPrefix, 0, len(model))
+	for p := range model {
+		all = append(all, p)
+	}
+	sortNodes(all)
+	if got := collectWalk(b.Walk); !reflect.DeepEqual(got, all) {
+		return errors.Errorf("walk %v, expected %v", got, all)
+	}
+
+	for i := 0; i < 16; i++ {
+		p := randomPrefix(r, f)
+		v, ok := b.Get(p)
+		mv, mok := model[p]
+		if ok != mok || v != mv {
+			return errors.Errorf("get %s returned %v %t, expected %v %t", p, v, ok, mv, mok)
+		}
+
+		holds := []netaddr.IPPrefix{}
+		children := []netaddr.IPPrefix{}
+		for _, q := range all {
+			if q.Bits() <= p.Bits() && q.Contains(p.IP()) {
+				holds = append(holds, q)
+			}
+			if q.Bits() >= p.Bits() && p.Contains(q.IP()) {
+				children = append(children, q)
+			}
+		}
+		walkMatch := func(fn func(netaddr.IPPrefix, interface{}) bool) { b.WalkMatch(p, fn) }
+		if got := collectWalk(walkMatch); !reflect.DeepEqual(got, holds) {
+			return errors.Errorf("walk match %s %v, expected %v", p, got, holds)
+		}
+		walkPrefix := func(fn func(netaddr.IPPrefix, interface{}) bool) { b.WalkPrefix(p, fn) }
+		if got := collectWalk(walkPrefix); !reflect.DeepEqual(got, children) {
+			return errors.Errorf("walk prefix %s %v, expected %v", p, got, children)
+		}
+
+		ip := p.Range().To()
+		mp, mv, mok := netaddr.IPPrefix{}, interface{}(nil), false
+		for _, q := range all {
+			if q.Contains(ip) && (!mok || q.Bits() > mp.Bits()) {
+				mp, mv, mok = q, model[q], true
+			}
+		}
+		if mp2, v, ok := b.Match(ip); ok != mok || mp2 != mp || v != mv {
+			return errors.Errorf("match %s returned %s %v %t, expected %s %v %t", ip, mp2, v, ok, mp, mv, mok)
+		}
+	}
+	return nil
+}
+
+// sortNodes sorts prefixes in the walk order of a backend
+func sortNodes(prefixes []netaddr.IPPrefix) {
+	sort.Slice(prefixes, func(i, j int) bool {
+		if prefixes[i].IP() != prefixes[j].IP() {
+			return prefixes[i].IP().Less(prefixes[j].IP())
+		}
+		return prefixes[i].Bits() < prefixes[j].Bits()
+	})
+}
+
+func collectWalk(walk func(func(netaddr.IPPrefix, interface{}) bool)) []netaddr.IPPrefix {
+	prefixes := []netaddr.IPPrefix{}
+	walk(func(p netaddr.IPPrefix, _ interface{}) bool {
+		prefixes = append(prefixes, p)
+		return true
+	})
+	return prefixes
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"inet.af/netaddr"
+)
+
+// apiBenchmark runs fn for every backend on a tree with 1000 /24 prefixes,
+// fn gets the prefixes of the looked up addresses both parsed and as keys
+func apiBenchmark(b *testing.B, fn func(b *testing.B, ipam *IpTree[string], prefixes []netaddr.IPPrefix, keys []string)) {
+	backends := []struct {
+		name string
+		new  func() Backend
+	}{
+		{"critbit", NewCritbitBackend},
+		{"radix", NewRadixBackend},
+	}
+	for _, bk := range backends {
+		b.Run(bk.name, func(b *testing.B) {
+			n := 1000
+			ipam := benchTree(b, n, WithBackend(bk.new))
+			ips := benchAddresses(n)
+			prefixes := make([]netaddr.IPPrefix, len(ips))
+			keys := make([]string, len(ips))
+			for i, ip := range ips {
+				prefixes[i], _ = ip.Prefix(24)
+				keys[i] = prefixes[i].String()
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			fn(b, ipam, prefixes, keys)
+		})
+	}
+}
+
+// the string api parses its arguments on every call, the netaddr api takes
+// them parsed
+
+func BenchmarkGetPrefix(b *testing.B) {
+	apiBenchmark(b, func(b *testing.B, ipam *IpTree[string], _ []netaddr.IPPrefix, keys []string) {
+		for i := 0; i < b.N; i++ {
+			ipam.GetPrefix(keys[i%len(keys)])
+		}
+	})
+}
+
+func BenchmarkGetIPPrefix(b *testing.B) {
+	apiBenchmark(b, func(b *testing.B, ipam *IpTree[string], prefixes []netaddr.IPPrefix, _ []string) {
+		for i := 0; i < b.N; i++ {
+			ipam.GetIPPrefix(prefixes[i%len(prefixes)])
+		}
+	})
+}
+
+func BenchmarkAddPrefix(b *testing.B) {
+	apiBenchmark(b, func(b *testing.B, ipam *IpTree[string], _ []netaddr.IPPrefix, keys []string) {
+		for i := 0; i < b.N; i++ {
+			ipam.AddPrefix(keys[i%len(keys)], "update")
+		}
+	})
+}
+
+func BenchmarkAddIPPrefix(b *testing.B) {
+	apiBenchmark(b, func(b *testing.B, ipam *IpTree[string], prefixes []netaddr.IPPrefix, _ []string) {
+		for i := 0; i < b.N; i++ {
+			ipam.AddIPPrefix(prefixes[i%len(prefixes)], "update")
+		}
+	})
+}
+
+// scaleSize is the amount of prefixes below the root prefix of the trees
+// of BenchmarkScale
+const scaleSize = 1 << 16
+
+// scalePrefix returns prefix i of the trees of BenchmarkScale, the ipv4
+// prefixes are the 2^20 /28 prefixes of 10.0.0.0/8 and the ipv6 prefixes
+// the 2^32 /64 prefixes of 2001:db8::/32
+func scalePrefix(f Family, i int) (netaddr.IPPrefix, error) {
+	if f == FamilyIPv4 {
+		if i < 0 || i >= 1<<20 {
+			return netaddr.IPPrefix{}, errors.Wrapf(ErrExhausted, "10.0.0.0/8 holds no /28 prefix %d", i)
+		}
+		return netaddr.IPPrefixFrom(netaddr.IPv4(10, byte(i>>12), byte(i>>4), byte(i<<4)), 28), nil
+	}
+	if i < 0 || uint64(i) >= 1<<32 {
+		return netaddr.IPPrefix{}, errors.Wrapf(ErrExhausted, "2001:db8::/32 holds no /64 prefix %d", i)
+	}
+	a := [16]byte{0x20, 0x01, 0x0d, 0xb8, byte(i >> 24), byte(i >> 16), byte(i >> 8), byte(i)}
+	return netaddr.IPPrefixFrom(netaddr.IPFrom16(a), 64), nil
+}
+
+// scaleTree returns a tree of address family f with a root prefix and n
+// unique prefixes below it
+func scaleTree(tb testing.TB, f Family, n int, opts ...Option) *IpTree[string] {
+	tb.Helper()
+	ipam := New[string](opts...)
+	root := netaddr.MustParseIPPrefix("10.0.0.0/8")
+	if f == FamilyIPv6 {
+		root = netaddr.MustParseIPPrefix("2001:db8::/32")
+	}
+	if err := ipam.AddIPPrefix(root, "root"); err != nil {
+		tb.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		p, err := scalePrefix(f, i)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		if err := ipam.AddIPPrefix(p, "child"); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return ipam
+}
+
+func TestScalePrefix(t *testing.T) {
+	for _, f := range Families {
+		seen := map[netaddr.IPPrefix]struct{}{}
+		for i := 0; i < 1<<12; i++ {
+			p, err := scalePrefix(f, i*255)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := seen[p]; ok {
+				t.Fatalf("%s prefix %d is %s again", f, i*255, p)
+			}
+			seen[p] = struct{}{}
+		}
+	}
+	if _, err := scalePrefix(FamilyIPv4, 1<<20); errors.Cause(err) != ErrExhausted {
+		t.Errorf("prefix 2^20 of 10.0.0.0/8 returned %v", err)
+	}
+}
+
+// BenchmarkScale builds a tree with scaleSize prefixes for every address
+// family on the critbit and the radix backend and benchmarks the
+// operations of the api on it
+func BenchmarkScale(b *testing.B) {
+	backends := []struct {
+		name string
+		new  func() Backend
+	}{
+		{"critbit", NewCritbitBackend},
+		{"radix", NewRadixBackend},
+	}
+	for _, bk := range backends {
+		for _, f := range Families {
+			b.Run(bk.name+"/"+f.String(), func(b *testing.B) {
+				ipam := scaleTree(b, f, scaleSize, WithBackend(bk.new))
+				prefixes := make([]netaddr.IPPrefix, 1024)
+				ips := make([]netaddr.IP, len(prefixes))
+				ranges := make([]netaddr.IPRange, len(prefixes))
+				for i := range prefixes {
+					prefixes[i], _ = scalePrefix(f, i*7919%scaleSize)
+					ips[i] = prefixes[i].IP().Next()
+					ranges[i] = netaddr.IPRangeFrom(ips[i], ips[i].Next().Next())
+				}
+				t := ipam.GetTree(f)
+				b.Run("GetIPPrefix", func(b *testing.B) {
+					b.ReportAllocs()
+					for i := 0; i < b.N; i++ {
+						ipam.GetIPPrefix(prefixes[i%len(prefixes)])
+					}
+				})
+				b.Run("Match", func(b *testing.B) {
+					b.ReportAllocs()
+					for i := 0; i < b.N; i++ {
+						t.Match(ips[i%len(ips)])
+					}
+				})
+				b.Run("AddIPPrefix", func(b *testing.B) {
+					b.ReportAllocs()
+					for i := 0; i < b.N; i++ {
+						ipam.AddIPPrefix(prefixes[i%len(prefixes)], "update")
+					}
+				})
+				b.Run("AddIPRange", func(b *testing.B) {
+					b.ReportAllocs()
+					for i := 0; i < b.N; i++ {
+						r := ranges[i%len(ranges)]
+						ipam.AddIPRange(r, "range")
+						ipam.DeleteIPRange(r)
+					}
+				})
+				b.Run("Invariants", func(b *testing.B) {
+					b.ReportAllocs()
+					for i := 0; i < b.N; i++ {
+						ipam.CheckInvariants()
+					}
+				})
+			})
+		}
+	}
+}
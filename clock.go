@@ -0,0 +1,65 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock returns the current time, it is injected with WithClock to make the
+// expiry of leases deterministic in tests
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ManualClock is a clock that only moves when it is set or advanced
+type ManualClock struct {
+	m   sync.Mutex
+	now time.Time
+}
+
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{
+		now: now,
+	}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.now
+}
+
+// Set sets the time of the clock
+func (c *ManualClock) Set(now time.Time) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.now = now
+}
+
+// Advance moves the clock forward by d
+func (c *ManualClock) Advance(d time.Duration) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// WithClock sets the clock of the tree, the system clock is used by default
+func WithClock(c Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// now returns the current time of the clock of the tree
+func (ipam *IpTree[T]) now() time.Time {
+	if ipam.opts.clock == nil {
+		return systemClock{}.Now()
+	}
+	return ipam.opts.clock.Now()
+}
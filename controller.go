@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/henderiw/lpm/ipamv1alpha1"
+	"github.com/pkg/errors"
+	"inet.af/netaddr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// conflictRequeue is the delay after which a resource that was rejected by
+// the policy is reconciled again, e.g. a claim created before its prefix
+const conflictRequeue = 30 * time.Second
+
+// IpamController reconciles the IPPrefix, IPRange and IPClaim resources of
+// a cluster into an ipam tree, the value of an entry in the tree is the
+// kind, namespace and name of the resource that owns it
+type IpamController struct {
+	client client.Client
+	m      sync.Mutex
+	ipam   *IpTree[string]
+	// keys holds the key in the tree of every reconciled resource
+	keys map[string]string
+}
+
+func NewIpamController(c client.Client, ipam *IpTree[string]) *IpamController {
+	return &IpamController{
+		client: c,
+		ipam:   ipam,
+		keys:   map[string]string{},
+	}
+}
+
+// NewScheme returns a scheme with the ipam resources
+func NewScheme() (*runtime.Scheme, error) {
+	s := runtime.NewScheme()
+	if err := ipamv1alpha1.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// RunController reconciles the ipam resources of the cluster of the current
+// kubeconfig into the tree until the context is done
+func RunController(ctx context.Context, ipam *IpTree[string]) error {
+	s, err := NewScheme()
+	if err != nil {
+		return err
+	}
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return errors.Wrap(err, "cannot get kubeconfig")
+	}
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{Scheme: s})
+	if err != nil {
+		return errors.Wrap(err, "cannot create manager")
+	}
+	if err := NewIpamController(mgr.GetClient(), ipam).SetupWithManager(mgr); err != nil {
+		return err
+	}
+	return mgr.Start(ctx)
+}
+
+// SetupWithManager registers a controller for every ipam resource with the
+// manager
+func (r *IpamController) SetupWithManager(mgr ctrl.Manager) error {
+	controllers := []struct {
+		name string
+		obj  client.Object
+		fn   reconcile.Func
+	}{
+		{name: "ipprefix", obj: &ipamv1alpha1.IPPrefix{}, fn: r.ReconcilePrefix},
+		{name: "iprange", obj: &ipamv1alpha1.IPRange{}, fn: r.ReconcileRange},
+		{name: "ipclaim", obj: &ipamv1alpha1.IPClaim{}, fn: r.ReconcileClaim},
+	}
+	for _, c := range controllers {
+		if err := ctrl.NewControllerManagedBy(mgr).Named(c.name).For(c.obj).Complete(c.fn); err != nil {
+			return errors.Wrapf(err, "cannot setup %s controller", c.name)
+		}
+	}
+	return nil
+}
+
+// ReconcilePrefix stores the prefix of an IPPrefix resource in the tree
+func (r *IpamController) ReconcilePrefix(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	id := "IPPrefix/" + req.String()
+
+	cr := &ipamv1alpha1.IPPrefix{}
+	if err := r.client.Get(ctx, req.NamespacedName, cr); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, r.release(id)
+		}
+		return reconcile.Result{}, err
+	}
+	err := r.storePrefix(id, cr.Spec.Prefix)
+	setCondition(&cr.Status, cr.Generation, err)
+	return r.updateStatus(ctx, cr, err)
+}
+
+// ReconcileRange stores the range of an IPRange resource in the tree
+func (r *IpamController) ReconcileRange(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	id := "IPRange/" + req.String()
+
+	cr := &ipamv1alpha1.IPRange{}
+	if err := r.client.Get(ctx, req.NamespacedName, cr); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, r.release(id)
+		}
+		return reconcile.Result{}, err
+	}
+	err := r.storeRange(id, cr.Spec.Range)
+	setCondition(&cr.Status, cr.Generation, err)
+	return r.updateStatus(ctx, cr, err)
+}
+
+// ReconcileClaim allocates the ip address of an IPClaim resource and writes
+// it to the status of the resource
+func (r *IpamController) ReconcileClaim(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	id := "IPClaim/" + req.String()
+
+	cr := &ipamv1alpha1.IPClaim{}
+	if err := r.client.Get(ctx, req.NamespacedName, cr); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, r.release(id)
+		}
+		return reconcile.Result{}, err
+	}
+	address, err := r.storeClaim(id, cr.Spec, cr.Status.Address)
+	cr.Status.Address = address
+	setCondition(&cr.Status.IPStatus, cr.Generation, err)
+	return r.updateStatus(ctx, cr, err)
+}
+
+// storePrefix adds prefix p to the tree for the resource id, the previous
+// key of the resource is released when the spec changed
+func (r *IpamController) storePrefix(id, p string) error {
+	p, _, err := Canonicalize(p)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(p, "/") {
+		return errors.Wrapf(ErrInvalid, "%s is not a prefix", p)
+	}
+	if err := r.releaseChanged(id, p); err != nil {
+		return err
+	}
+	if owner, ok := r.ipam.GetPrefix(p); ok {
+		if owner == id {
+			return nil
+		}
+		return errors.Wrapf(ErrOverlap, "prefix %s is owned by %s", p, owner)
+	}
+	if ok, err := r.ipam.PreCheckAddition(p); !ok {
+		if err == nil {
+			err = errors.Wrapf(ErrOverlap, "prefix %s is rejected by the policy", p)
+		}
+		return err
+	}
+	if err := r.ipam.AddPrefix(p, id); err != nil {
+		return err
+	}
+	r.keys[id] = p
+	return nil
+}
+
+// storeRange adds range ra to the tree for the resource id, the previous
+// key of the resource is released when the spec changed
+func (r *IpamController) storeRange(id, ra string) error {
+	ra, _, err := Canonicalize(ra)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(ra, "-") {
+		return errors.Wrapf(ErrInvalid, "%s is not a range", ra)
+	}
+	if err := r.releaseChanged(id, ra); err != nil {
+		return err
+	}
+	if owner, ok := r.ipam.GetRange(ra); ok {
+		if owner == id {
+			return nil
+		}
+		return errors.Wrapf(ErrOverlap, "range %s is owned by %s", ra, owner)
+	}
+	if ok, err := r.ipam.PreCheckAddition(ra); !ok {
+		if err == nil {
+			err = errors.Wrapf(ErrOverlap, "range %s is rejected by the policy", ra)
+		}
+		return err
+	}
+	if err := r.ipam.AddRange(ra, id); err != nil {
+		return err
+	}
+	r.keys[id] = ra
+	return nil
+}
+
+// storeClaim allocates an ip address for the resource id, the address of
+// the spec is claimed when it is set, otherwise the address in the status
+// is kept so a restarted controller hands out the same addresses
+func (r *IpamController) storeClaim(id string, spec ipamv1alpha1.IPClaimSpec, current string) (string, error) {
+	from, _, err := Canonicalize(spec.From)
+	if err != nil {
+		return "", err
+	}
+	fromRange, err := keyRange(from)
+	if err != nil {
+		return "", errors.Wrapf(ErrInvalid, "%s: %v", from, err)
+	}
+	want := spec.Address
+	if want == "" {
+		want = current
+	}
+	if want != "" {
+		want, _, err := Canonicalize(want)
+		if err != nil {
+			return "", err
+		}
+		ip, err := netaddr.ParseIP(want)
+		if err != nil {
+			return "", errors.Wrapf(ErrInvalid, "cannot parse ip address %s: %v", want, err)
+		}
+		switch {
+		case fromRange.Contains(ip):
+			err := r.claimIP(id, ip.String())
+			if err == nil {
+				return ip.String(), nil
+			}
+			if spec.Address != "" {
+				return "", err
+			}
+		case spec.Address != "":
+			return "", errors.Wrapf(ErrNoParent, "%s is not in %s", ip, from)
+		}
+	}
+	// the address in the status is lost, allocate a new one
+	if err := r.release(id); err != nil {
+		return "", err
+	}
+	ip, err := r.ipam.AllocateIP(from, id)
+	if err != nil {
+		return "", err
+	}
+	r.keys[id] = ip
+	return ip, nil
+}
+
+// claimIP claims ip address ip for the resource id
+func (r *IpamController) claimIP(id, ip string) error {
+	if owner, ok := r.ipam.GetIP(ip); ok {
+		if owner == id {
+			r.keys[id] = ip
+			return nil
+		}
+		return errors.Wrapf(ErrOverlap, "ip address %s is owned by %s", ip, owner)
+	}
+	if err := r.releaseChanged(id, ip); err != nil {
+		return err
+	}
+	if err := r.ipam.ClaimIP(ip, id); err != nil {
+		return err
+	}
+	r.keys[id] = ip
+	return nil
+}
+
+// releaseChanged releases the key of the resource id when it is not k
+func (r *IpamController) releaseChanged(id, k string) error {
+	if old, ok := r.keys[id]; ok && old != k {
+		return r.release(id)
+	}
+	return nil
+}
+
+// release removes the key of the resource id from the tree, a prefix with
+// children is kept until its children are removed
+func (r *IpamController) release(id string) error {
+	k, ok := r.keys[id]
+	if !ok {
+		return nil
+	}
+	var err error
+	switch {
+	case strings.Contains(k, "-"):
+		err = r.ipam.DeleteRange(k)
+	case strings.Contains(k, "/"):
+		_, err = r.ipam.DeletePrefixWithOptions(k, DeleteOptions{Mode: DeleteStrict})
+	default:
+		err = r.ipam.ReleaseIP(k)
+	}
+	if err != nil && errors.Cause(err) != ErrNotFound {
+		return errors.Wrapf(err, "cannot release %s of %s", k, id)
+	}
+	delete(r.keys, id)
+	return nil
+}
+
+// updateStatus writes the status of the resource, a rejection by the policy
+// is reported in the status and retried later, other errors are returned
+// to retry with backoff
+func (r *IpamController) updateStatus(ctx context.Context, cr client.Object, err error) (reconcile.Result, error) {
+	if serr := r.client.Status().Update(ctx, cr); serr != nil {
+		return reconcile.Result{}, errors.Wrap(serr, "cannot update status")
+	}
+	switch cause := errors.Cause(err); {
+	case err == nil, cause == ErrInvalid, cause == ErrMixedFamily:
+		// an invalid spec is only reconciled again when it changes
+		return reconcile.Result{}, nil
+	case IsConflict(err), cause == ErrExhausted:
+		return reconcile.Result{RequeueAfter: conflictRequeue}, nil
+	}
+	return reconcile.Result{}, err
+}
+
+// setCondition sets the ready condition of a resource from the outcome of
+// its reconciliation
+func setCondition(s *ipamv1alpha1.IPStatus, generation int64, err error) {
+	c := metav1.Condition{
+		Type:               ipamv1alpha1.ConditionTypeReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Stored",
+		Message:            "stored in the ipam tree",
+		ObservedGeneration: generation,
+	}
+	if err != nil {
+		c.Status = metav1.ConditionFalse
+		c.Reason = conditionReason(err)
+		c.Message = err.Error()
+	}
+	s.ObservedGeneration = generation
+	meta.SetStatusCondition(&s.Conditions, c)
+}
+
+// conditionReason returns the reason of an error in the camel case notation
+// of a condition, e.g. inside-range becomes InsideRange
+func conditionReason(err error) string {
+	reason := ""
+	for _, w := range strings.Split(ErrorReason(err), "-") {
+		reason += strings.ToUpper(w[:1]) + w[1:]
+	}
+	return reason
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/henderiw/lpm/ipamv1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// newTestController returns a controller of an empty tree backed by a fake
+// client that holds the objects
+func newTestController(t *testing.T, objs ...client.Object) (*IpamController, client.Client) {
+	t.Helper()
+	s, err := NewScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(s).
+		WithObjects(objs...).
+		WithStatusSubresource(&ipamv1alpha1.IPPrefix{}, &ipamv1alpha1.IPRange{}, &ipamv1alpha1.IPClaim{}).
+		Build()
+	return NewIpamController(c, New[string]()), c
+}
+
+func request(name string) reconcile.Request {
+	return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: name}}
+}
+
+func objectMeta(name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Namespace: "default", Name: name}
+}
+
+// readyReason returns the reason of the ready condition of the status
+func readyReason(t *testing.T, s ipamv1alpha1.IPStatus) string {
+	t.Helper()
+	c := meta.FindStatusCondition(s.Conditions, ipamv1alpha1.ConditionTypeReady)
+	if c == nil {
+		t.Fatal("no ready condition")
+	}
+	return c.Reason
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	r, c := newTestController(t,
+		&ipamv1alpha1.IPPrefix{ObjectMeta: objectMeta("net"), Spec: ipamv1alpha1.IPPrefixSpec{Prefix: "10.0.0.0/24"}},
+		&ipamv1alpha1.IPRange{ObjectMeta: objectMeta("pool"), Spec: ipamv1alpha1.IPRangeSpec{Range: "10.0.0.10-10.0.0.20"}},
+		&ipamv1alpha1.IPClaim{ObjectMeta: objectMeta("dyn"), Spec: ipamv1alpha1.IPClaimSpec{From: "10.0.0.10-10.0.0.20"}},
+		&ipamv1alpha1.IPClaim{ObjectMeta: objectMeta("static"), Spec: ipamv1alpha1.IPClaimSpec{From: "10.0.0.0/24", Address: "10.0.0.5"}},
+	)
+	steps := []struct {
+		name string
+		fn   func(context.Context, reconcile.Request) (reconcile.Result, error)
+	}{
+		{"net", r.ReconcilePrefix},
+		{"pool", r.ReconcileRange},
+		{"dyn", r.ReconcileClaim},
+		{"static", r.ReconcileClaim},
+	}
+	for _, s := range steps {
+		if res, err := s.fn(ctx, request(s.name)); err != nil || res.RequeueAfter != 0 {
+			t.Fatalf("reconcile %s returned %v %v", s.name, res, err)
+		}
+	}
+
+	prefix := &ipamv1alpha1.IPPrefix{}
+	if err := c.Get(ctx, request("net").NamespacedName, prefix); err != nil {
+		t.Fatal(err)
+	}
+	if reason := readyReason(t, prefix.Status); reason != "Stored" {
+		t.Errorf("prefix has reason %s", reason)
+	}
+	for name, want := range map[string]string{"dyn": "10.0.0.10", "static": "10.0.0.5"} {
+		claim := &ipamv1alpha1.IPClaim{}
+		if err := c.Get(ctx, request(name).NamespacedName, claim); err != nil {
+			t.Fatal(err)
+		}
+		if claim.Status.Address != want {
+			t.Errorf("claim %s has address %s, want %s", name, claim.Status.Address, want)
+		}
+		if owner, _ := r.ipam.GetIP(want); owner != "IPClaim/default/"+name {
+			t.Errorf("%s is owned by %q", want, owner)
+		}
+	}
+
+	// a deleted claim releases its address
+	if err := c.Delete(ctx, &ipamv1alpha1.IPClaim{ObjectMeta: objectMeta("dyn")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReconcileClaim(ctx, request("dyn")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.ipam.GetIP("10.0.0.10"); ok {
+		t.Error("10.0.0.10 is still allocated")
+	}
+}
+
+func TestReconcileRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		fn      func(*IpamController) func(context.Context, reconcile.Request) (reconcile.Result, error)
+		obj     client.Object
+		reason  string
+		requeue time.Duration
+	}{
+		{
+			name: "range without parent",
+			fn: func(r *IpamController) func(context.Context, reconcile.Request) (reconcile.Result, error) {
+				return r.ReconcileRange
+			},
+			obj: &ipamv1alpha1.IPRange{ObjectMeta: objectMeta("x"), Spec: ipamv1alpha1.IPRangeSpec{Range: "10.0.0.10-10.0.0.20"}},
+			// the policy rejects the range with ErrNoParent
+			reason:  "NoParent",
+			requeue: conflictRequeue,
+		},
+		{
+			name: "range as prefix",
+			fn: func(r *IpamController) func(context.Context, reconcile.Request) (reconcile.Result, error) {
+				return r.ReconcilePrefix
+			},
+			obj:    &ipamv1alpha1.IPPrefix{ObjectMeta: objectMeta("x"), Spec: ipamv1alpha1.IPPrefixSpec{Prefix: "10.0.0.10-10.0.0.20"}},
+			reason: "ParseError",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			r, c := newTestController(t, tt.obj)
+			res, err := tt.fn(r)(ctx, request("x"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.RequeueAfter != tt.requeue {
+				t.Errorf("requeue after %s, want %s", res.RequeueAfter, tt.requeue)
+			}
+			if err := c.Get(ctx, request("x").NamespacedName, tt.obj); err != nil {
+				t.Fatal(err)
+			}
+			var s ipamv1alpha1.IPStatus
+			switch o := tt.obj.(type) {
+			case *ipamv1alpha1.IPPrefix:
+				s = o.Status
+			case *ipamv1alpha1.IPRange:
+				s = o.Status
+			}
+			if reason := readyReason(t, s); reason != tt.reason {
+				t.Errorf("reason %s, want %s", reason, tt.reason)
+			}
+			if n := len(r.ipam.Export()); n != 0 {
+				t.Errorf("tree holds %d entries", n)
+			}
+		})
+	}
+}
+
+func TestStoreRejectedByPolicy(t *testing.T) {
+	r, _ := newTestController(t)
+	if err := r.storePrefix("IPPrefix/default/net", "10.0.0.0/24"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.storeRange("IPRange/default/a", "10.0.0.10-10.0.0.20"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.storeRange("IPRange/default/b", "10.0.0.15-10.0.0.30"); err == nil {
+		t.Error("overlapping range was stored")
+	}
+	if err := r.storePrefix("IPPrefix/default/c", "10.0.0.16/30"); err == nil {
+		t.Error("prefix inside a range was stored")
+	}
+	if _, ok := r.keys["IPRange/default/b"]; ok {
+		t.Error("rejected range has a key")
+	}
+}
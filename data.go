@@ -1,23 +1,25 @@
 package main
 
-type Data struct {
+// Data is the information stored in a tree node, the values are typed by
+// the application using the ipam tree
+type Data[T any] struct {
 	meta  *Metadata
-	value map[string]interface{} // string is the key for the prefix or range or ip address
+	value map[string]T // string is the key for the prefix or range or ip address
 }
 
-func (d *Data) GetMeta() *Metadata {
+func (d *Data[T]) GetMeta() *Metadata {
 	return d.meta
 }
 
-func (d *Data) GetValue() map[string]interface{} {
+func (d *Data[T]) GetValue() map[string]T {
 	return d.value
 }
 
-func (d *Data) AddValue(p string, v interface{}) {
+func (d *Data[T]) AddValue(p string, v T) {
 	d.value[p] = v
 }
 
-func (d *Data) DeleteValue(p string) {
+func (d *Data[T]) DeleteValue(p string) {
 	delete(d.value, p)
 }
 
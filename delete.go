@@ -0,0 +1,281 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"inet.af/netaddr"
+)
+
+// DeleteMode defines how the entries below a deleted prefix are handled
+type DeleteMode int
+
+const (
+	// DeleteReparent removes the prefix only, the entries below it move
+	// up to the parent of the deleted prefix, it is the default mode
+	DeleteReparent DeleteMode = iota
+	// DeleteStrict fails when prefixes, ranges or ip addresses exist below
+	// the prefix
+	DeleteStrict
+	// DeleteCascade removes the prefix together with everything below it
+	DeleteCascade
+)
+
+func (m DeleteMode) String() string {
+	switch m {
+	case DeleteStrict:
+		return "strict"
+	case DeleteCascade:
+		return "cascade"
+	case DeleteReparent:
+		return "reparent"
+	}
+	return "unknown"
+}
+
+// DeleteOptions control the deletion of a prefix
+type DeleteOptions struct {
+	Mode DeleteMode
+	// DryRun reports the affected entries without changing the tree
+	DryRun bool
+}
+
+// DeleteResult reports the entries affected by the deletion of a prefix
+type DeleteResult struct {
+	// Deleted are the keys of the removed entries, most specific first
+	Deleted []string
+	// Reparented are the keys of the entries that moved to Parent
+	Reparented []string
+	// Parent is the new parent of the reparented entries, empty when the
+	// entries have no parent anymore
+	Parent string
+	// Blocking are the keys of the entries that prevent a strict deletion
+	Blocking []string
+}
+
+// DeletePrefixWithOptions deletes a prefix using the delete mode of the
+// options, the zero mode reparents like DeletePrefix. With DryRun set the
+// affected entries are returned without deleting them
+func (ipam *IpTree[T]) DeletePrefixWithOptions(p string, o DeleteOptions) (_ *DeleteResult, err error) {
+	defer ipam.metrics.observe(opDeletePrefix, &err)
+	p, _, err = ipam.tree(p)
+	if err != nil {
+		return nil, err
+	}
+	pfx, err := netaddr.ParseIPPrefix(p)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot parse prefix")
+	}
+	if _, ok := ipam.GetPrefix(p); !ok {
+		return nil, errors.Wrapf(ErrNotFound, "prefix %s", p)
+	}
+	children := ipam.subtree(pfx)
+	result := &DeleteResult{}
+
+	switch o.Mode {
+	case DeleteStrict:
+		if len(children) > 0 {
+			result.Blocking = children
+			return result, errors.Wrapf(ErrHasChildren, "prefix %s has %d children", p, len(children))
+		}
+		result.Deleted = []string{p}
+	case DeleteCascade:
+		result.Deleted = append(children, p)
+	case DeleteReparent:
+		if err := ipam.checkDeletePrefix(pfx); err != nil {
+			return result, err
+		}
+		result.Deleted = []string{p}
+		result.Reparented = directChildren(pfx, children)
+		if parents := ipam.Parents(pfx); len(parents) > 0 {
+			result.Parent = parents[len(parents)-1].String()
+		}
+	default:
+		return nil, errors.Errorf("unknown delete mode %d", o.Mode)
+	}
+	if o.DryRun {
+		return result, nil
+	}
+
+	// the entries are validated before the first one is deleted and the
+	// deleted entries are restored when a deletion fails, such that a
+	// cascade is deleted either completely or not at all
+	values := make([]T, len(result.Deleted))
+	leases := make([]*Lease, len(result.Deleted))
+	for i, k := range result.Deleted {
+		v, ok := ipam.entryValue(k)
+		if !ok {
+			return result, errors.Wrapf(ErrNotFound, "cannot delete %s", k)
+		}
+		values[i] = v
+		leases[i], _ = ipam.leases.get(k)
+	}
+	for i, k := range result.Deleted {
+		if err := ipam.deleteEntry(k); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				if rerr := ipam.restoreEntry(result.Deleted[j], values[j], leases[j]); rerr != nil {
+					return result, errors.Wrapf(rerr, "cannot restore %s after deleting %s failed with %v", result.Deleted[j], k, err)
+				}
+			}
+			return result, errors.Wrapf(err, "cannot delete %s", k)
+		}
+	}
+	return result, nil
+}
+
+// entryValue returns the value stored for the prefix, range or ip address
+// with key k
+func (ipam *IpTree[T]) entryValue(k string) (T, bool) {
+	switch {
+	case strings.Contains(k, "-"):
+		return ipam.GetRange(k)
+	case strings.Contains(k, "/"):
+		return ipam.GetPrefix(k)
+	default:
+		return ipam.GetIP(k)
+	}
+}
+
+// restoreEntry adds a deleted prefix, range or ip address with key k back
+// to the tree together with its lease, the policy is not enforced as the
+// entry was part of the tree before
+func (ipam *IpTree[T]) restoreEntry(k string, value T, lease *Lease) error {
+	var old T
+	switch {
+	case strings.Contains(k, "-"):
+		r, err := ParseRange(k)
+		if err != nil {
+			return err
+		}
+		ipam.rangeIndex(familyOfIP(r.From())).insert(r, k, value)
+		return ipam.emit(EventAdded, k, old, value)
+	case strings.Contains(k, "/"):
+		p, err := ParsePrefix(k)
+		if err != nil {
+			return err
+		}
+		return ipam.insertPrefix(p, value)
+	default:
+		ip, err := netaddr.ParseIP(k)
+		if err != nil {
+			return errors.Wrap(err, "cannot parse ip address")
+		}
+		if err := ipam.addValue(netaddr.IPPrefixFrom(ip, ip.BitLen()), k, value, (*Metadata).SetIpAddress); err != nil {
+			return err
+		}
+		if err := ipam.emit(EventAllocated, k, old, value); err != nil {
+			return err
+		}
+		if lease != nil {
+			ipam.leases.add(lease)
+		}
+	}
+	return nil
+}
+
+// deleteEntry removes the prefix, range or ip address with key from the tree
+func (ipam *IpTree[T]) deleteEntry(k string) error {
+	switch {
+	case strings.Contains(k, "-"):
+		return ipam.DeleteRange(k)
+	case strings.Contains(k, "/"):
+		return ipam.deletePrefix(k)
+	default:
+		return ipam.releaseAddress(k, EventReleased)
+	}
+}
+
+// releaseAddress removes the ip address with key k and its lease from the
+// tree, the release is emitted as an event of type t
+func (ipam *IpTree[T]) releaseAddress(k string, t EventType) error {
+	ip, err := netaddr.ParseIP(k)
+	if err != nil {
+		return errors.Wrap(err, "cannot parse ip address")
+	}
+	old, exists := ipam.GetIP(k)
+	if err := ipam.deleteValue(netaddr.IPPrefixFrom(ip, ip.BitLen()), k); err != nil {
+		return err
+	}
+	if !exists {
+		ipam.leases.delete(k)
+		return nil
+	}
+	var value T
+	if err := ipam.emit(t, k, old, value); err != nil {
+		return err
+	}
+	ipam.leases.delete(k)
+	return nil
+}
+
+// subtree returns the keys of the prefixes, ranges and ip addresses that
+// are stored below prefix p, the most specific entries are returned first
+func (ipam *IpTree[T]) subtree(p netaddr.IPPrefix) []string {
+	t := ipam.GetTree(familyOfIP(p.IP()))
+	pr := p.Range()
+	keys := map[string]int{}
+	t.WalkPrefix(p, func(_ netaddr.IPPrefix, v interface{}) bool {
+		d, ok := ipam.getData(v)
+		if !ok {
+			return true
+		}
+		for k := range d.GetValue() {
+			switch {
+			case strings.Contains(k, "/"):
+				if o, err := netaddr.ParseIPPrefix(k); err == nil && o != p && p.Contains(o.IP()) {
+					keys[k] = int(o.Bits())
+				}
+			default:
+				// ip addresses are deleted first
+				keys[k] = 256
+			}
+		}
+		return true
+	})
+	ipam.rangesOverlapping(pr, func(n *rangeNode[T]) bool {
+		if rangeContains(pr, n.r) {
+			// ranges are deleted after the ip addresses
+			keys[n.key] = 255
+		}
+		return true
+	})
+
+	result := make([]string, 0, len(keys))
+	for k := range keys {
+		result = append(result, k)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if keys[result[i]] != keys[result[j]] {
+			return keys[result[i]] > keys[result[j]]
+		}
+		return result[i] < result[j]
+	})
+	return result
+}
+
+// directChildren returns the keys of the children for which prefix p is
+// the most specific parent prefix
+func directChildren(p netaddr.IPPrefix, children []string) []string {
+	prefixes := []netaddr.IPPrefix{}
+	for _, k := range children {
+		if o, err := netaddr.ParseIPPrefix(k); err == nil {
+			prefixes = append(prefixes, o)
+		}
+	}
+	result := []string{}
+	for _, k := range children {
+		r, _ := keyRange(k)
+		direct := true
+		for _, o := range prefixes {
+			if o.Range() != r && rangeContains(o.Range(), r) {
+				direct = false
+				break
+			}
+		}
+		if direct {
+			result = append(result, k)
+		}
+	}
+	return result
+}
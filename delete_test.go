@@ -0,0 +1,115 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// deleteTree returns a tree with a prefix that holds a child prefix, a
+// range and an allocated ip address
+func deleteTree(t *testing.T) *IpTree[string] {
+	t.Helper()
+	ipam := New[string]()
+	for _, p := range []string{"10.0.0.0/8", "10.0.0.0/16", "10.0.1.0/24"} {
+		if err := ipam.AddPrefix(p, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ipam.AddRange("10.0.0.10-10.0.0.20", "range"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ipam.ClaimIP("10.0.1.5", "host"); err != nil {
+		t.Fatal(err)
+	}
+	return ipam
+}
+
+func exportKeys(ipam *IpTree[string]) []string {
+	keys := []string{}
+	for _, e := range ipam.Export() {
+		keys = append(keys, e.Key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestDeletePrefixModes(t *testing.T) {
+	tests := []struct {
+		name string
+		mode DeleteMode
+		err  error
+		want []string
+	}{
+		{"strict", DeleteStrict, ErrHasChildren, []string{"10.0.0.0/16", "10.0.0.0/8", "10.0.0.10-10.0.0.20", "10.0.1.0/24", "10.0.1.5"}},
+		{"cascade", DeleteCascade, nil, []string{"10.0.0.0/8"}},
+		{"reparent", DeleteReparent, nil, []string{"10.0.0.0/8", "10.0.0.10-10.0.0.20", "10.0.1.0/24", "10.0.1.5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ipam := deleteTree(t)
+			_, err := ipam.DeletePrefixWithOptions("10.0.0.0/16", DeleteOptions{Mode: tt.mode})
+			if errors.Cause(err) != tt.err {
+				t.Fatalf("delete returned %v, want %v", err, tt.err)
+			}
+			if got := exportKeys(ipam); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("entries %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeletePrefixDryRun(t *testing.T) {
+	ipam := deleteTree(t)
+	before := exportKeys(ipam)
+	result, err := ipam.DeletePrefixWithOptions("10.0.0.0/16", DeleteOptions{Mode: DeleteCascade, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.1.5", "10.0.0.10-10.0.0.20", "10.0.1.0/24", "10.0.0.0/16"}
+	if !reflect.DeepEqual(result.Deleted, want) {
+		t.Errorf("deleted %v, want %v", result.Deleted, want)
+	}
+	if got := exportKeys(ipam); !reflect.DeepEqual(got, before) {
+		t.Errorf("dry run changed the tree to %v", got)
+	}
+}
+
+// DeletePrefix keeps its behavior from before the delete modes, the
+// entries below the prefix stay in the tree
+func TestDeletePrefixKeepsChildren(t *testing.T) {
+	ipam := deleteTree(t)
+	if err := ipam.DeletePrefix("10.0.0.0/16"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.0/8", "10.0.0.10-10.0.0.20", "10.0.1.0/24", "10.0.1.5"}
+	if got := exportKeys(ipam); !reflect.DeepEqual(got, want) {
+		t.Errorf("entries %v, want %v", got, want)
+	}
+}
+
+func TestRestoreEntry(t *testing.T) {
+	ipam := deleteTree(t)
+	want := exportKeys(ipam)
+	keys := []string{"10.0.1.5", "10.0.0.10-10.0.0.20", "10.0.1.0/24", "10.0.0.0/16"}
+	values := make([]string, len(keys))
+	for i, k := range keys {
+		values[i], _ = ipam.entryValue(k)
+		if err := ipam.deleteEntry(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := len(keys) - 1; i >= 0; i-- {
+		if err := ipam.restoreEntry(keys[i], values[i], nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := exportKeys(ipam); !reflect.DeepEqual(got, want) {
+		t.Errorf("entries %v, want %v", got, want)
+	}
+	if v, _ := ipam.GetRange("10.0.0.10-10.0.0.20"); v != "range" {
+		t.Errorf("restored range has value %q", v)
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/pkg/errors"
+	"inet.af/netaddr"
+)
+
+// offerTime is how long an offered address is held for a client that does
+// not request it
+const offerTime = time.Minute
+
+// DhcpLease is an ip address of a dhcp pool leased to a client, it is a
+// lease of the tree owned by the mac address of the client
+type DhcpLease struct {
+	MAC    string
+	IP     string
+	Pool   string
+	Expiry time.Time
+}
+
+// DhcpServer serves dhcpv4 leases from the ranges of an ipam tree, every
+// range is a pool and the leased addresses are allocated in the tree with a
+// lease, expired leases are released by the reaper of the tree
+type DhcpServer struct {
+	// l guards the tree, it is shared with the other servers of the tree
+	l         sync.Locker
+	ipam      *IpTree[string]
+	serverIP  net.IP
+	leaseTime time.Duration
+	pools     []string
+	// masks holds the subnet mask of every pool
+	masks map[string]net.IPMask
+}
+
+// NewDhcpServer returns a dhcp server with identifier serverIP that leases
+// the addresses of the ranges pools for leaseTime, the subnet mask of a
+// pool is the mask of the most specific prefix that holds the range. l
+// guards the tree when it is shared with the http or grpc server, e.g. the
+// Locker of that server, a nil l gives the dhcp server a lock of its own
+func NewDhcpServer(ipam *IpTree[string], l sync.Locker, serverIP net.IP, leaseTime time.Duration, pools ...string) (*DhcpServer, error) {
+	if serverIP.To4() == nil {
+		return nil, errors.Wrapf(ErrInvalid, "server ip %s is not an ipv4 address", serverIP)
+	}
+	if l == nil {
+		l = &sync.Mutex{}
+	}
+	s := &DhcpServer{
+		l:         l,
+		ipam:      ipam,
+		serverIP:  serverIP.To4(),
+		leaseTime: leaseTime,
+		masks:     map[string]net.IPMask{},
+	}
+	for _, pool := range pools {
+		pool, f, err := Canonicalize(pool)
+		if err != nil {
+			return nil, err
+		}
+		if f != FamilyIPv4 {
+			return nil, errors.Wrapf(ErrInvalid, "pool %s is not an ipv4 range", pool)
+		}
+		if !strings.Contains(pool, "-") {
+			return nil, errors.Wrapf(ErrInvalid, "pool %s is not a range", pool)
+		}
+		_, cover, err := ipam.parentRange(pool)
+		if err != nil {
+			return nil, err
+		}
+		s.pools = append(s.pools, pool)
+		s.masks[pool] = ipam.poolMask(cover)
+	}
+	if len(s.pools) == 0 {
+		return nil, errors.Wrap(ErrInvalid, "no dhcp pools")
+	}
+	return s, nil
+}
+
+// poolMask returns the mask of the most specific prefix that holds cover
+func (ipam *IpTree[T]) poolMask(cover netaddr.IPPrefix) net.IPMask {
+	for bits := int(cover.Bits()); bits >= 0; bits-- {
+		p, _ := cover.IP().Prefix(uint8(bits))
+		if _, ok := ipam.GetPrefix(p.String()); ok {
+			return p.IPNet().Mask
+		}
+	}
+	return cover.IPNet().Mask
+}
+
+// Leases returns the leases of the server sorted by ip address
+func (s *DhcpServer) Leases() []DhcpLease {
+	s.l.Lock()
+	defer s.l.Unlock()
+	leases := []DhcpLease{}
+	for _, l := range s.ipam.Leases() {
+		if pool, ok := s.pool(l.IP); ok {
+			leases = append(leases, DhcpLease{MAC: l.Owner, IP: l.IP, Pool: pool, Expiry: l.Expiry})
+		}
+	}
+	return leases
+}
+
+// pool returns the pool of ip address ip
+func (s *DhcpServer) pool(ip string) (string, bool) {
+	addr, err := netaddr.ParseIP(ip)
+	if err != nil {
+		return "", false
+	}
+	for _, pool := range s.pools {
+		if r, err := keyRange(pool); err == nil && r.Contains(addr) {
+			return pool, true
+		}
+	}
+	return "", false
+}
+
+// clientLease returns the lease of the client with mac address mac
+func (s *DhcpServer) clientLease(mac string) (*DhcpLease, bool) {
+	for _, l := range s.ipam.Leases() {
+		if l.Owner != mac {
+			continue
+		}
+		if pool, ok := s.pool(l.IP); ok {
+			return &DhcpLease{MAC: mac, IP: l.IP, Pool: pool, Expiry: l.Expiry}, true
+		}
+	}
+	return nil, false
+}
+
+// Handle returns the reply to a dhcp request, a nil reply is not sent
+func (s *DhcpServer) Handle(req *dhcpv4.DHCPv4) (*dhcpv4.DHCPv4, error) {
+	if req.OpCode != dhcpv4.OpcodeBootRequest {
+		return nil, nil
+	}
+	s.l.Lock()
+	defer s.l.Unlock()
+	mac := req.ClientHWAddr.String()
+
+	switch req.MessageType() {
+	case dhcpv4.MessageTypeDiscover:
+		l, err := s.lease(mac, req.RequestedIPAddress(), offerTime)
+		if err != nil {
+			return nil, err
+		}
+		return s.reply(req, dhcpv4.MessageTypeOffer, l)
+	case dhcpv4.MessageTypeRequest:
+		if sid := req.ServerIdentifier(); sid != nil && !sid.Equal(s.serverIP) {
+			// the client selected another server, the offer is withdrawn
+			return nil, s.release(mac)
+		}
+		requested := req.RequestedIPAddress()
+		if requested == nil {
+			// a renewing client has its address in ciaddr
+			requested = req.ClientIPAddr
+		}
+		if l, ok := s.clientLease(mac); !ok || !net.ParseIP(l.IP).Equal(requested) {
+			return dhcpv4.NewReplyFromRequest(req,
+				dhcpv4.WithMessageType(dhcpv4.MessageTypeNak),
+				dhcpv4.WithOption(dhcpv4.OptServerIdentifier(s.serverIP)),
+			)
+		}
+		l, err := s.lease(mac, requested, s.leaseTime)
+		if err != nil {
+			return nil, err
+		}
+		return s.reply(req, dhcpv4.MessageTypeAck, l)
+	case dhcpv4.MessageTypeRelease:
+		return nil, s.release(mac)
+	case dhcpv4.MessageTypeDecline:
+		l, ok := s.clientLease(mac)
+		if !ok {
+			return nil, nil
+		}
+		// the address is in use by another host, it stays allocated
+		// without a lease
+		if err := s.ipam.ReleaseIP(l.IP); err != nil {
+			return nil, err
+		}
+		return nil, s.ipam.ClaimIP(l.IP, "dhcp declined by "+mac)
+	}
+	return nil, nil
+}
+
+// lease returns the lease of the client with mac address mac, the lease is
+// extended by d. A new lease gets the requested address when it is free
+// and otherwise the first free address of the pools
+func (s *DhcpServer) lease(mac string, requested net.IP, d time.Duration) (*DhcpLease, error) {
+	if l, ok := s.clientLease(mac); ok {
+		if err := s.ipam.extendLease(l.IP, d); err != nil {
+			return nil, err
+		}
+		l.Expiry = s.ipam.now().Add(d)
+		return l, nil
+	}
+	value := "dhcp " + mac
+	if ip, ok := netaddr.FromStdIP(requested); ok {
+		if _, ok := s.pool(ip.String()); ok {
+			if err := s.ipam.ClaimIPWithLease(ip.String(), value, mac, d); err == nil {
+				l, _ := s.clientLease(mac)
+				return l, nil
+			}
+		}
+	}
+	for _, pool := range s.pools {
+		if _, err := s.ipam.AllocateIPWithLease(pool, value, mac, d); err != nil {
+			if errors.Cause(err) == ErrExhausted {
+				continue
+			}
+			return nil, err
+		}
+		l, _ := s.clientLease(mac)
+		return l, nil
+	}
+	return nil, errors.Wrapf(ErrExhausted, "dhcp pools %v", s.pools)
+}
+
+// release releases the lease of the client with mac address mac
+func (s *DhcpServer) release(mac string) error {
+	l, ok := s.clientLease(mac)
+	if !ok {
+		return nil
+	}
+	if err := s.ipam.ReleaseIP(l.IP); err != nil && errors.Cause(err) != ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+// reply returns an offer or ack of lease l
+func (s *DhcpServer) reply(req *dhcpv4.DHCPv4, t dhcpv4.MessageType, l *DhcpLease) (*dhcpv4.DHCPv4, error) {
+	return dhcpv4.NewReplyFromRequest(req,
+		dhcpv4.WithMessageType(t),
+		dhcpv4.WithYourIP(net.ParseIP(l.IP)),
+		dhcpv4.WithServerIP(s.serverIP),
+		dhcpv4.WithOption(dhcpv4.OptServerIdentifier(s.serverIP)),
+		dhcpv4.WithOption(dhcpv4.OptSubnetMask(s.masks[l.Pool])),
+		dhcpv4.WithOption(dhcpv4.OptIPAddressLeaseTime(l.Expiry.Sub(s.ipam.now()).Round(time.Second))),
+	)
+}
+
+// Serve serves dhcp on the packet connection until it fails, a reply to a
+// client without an address is broadcast
+func (s *DhcpServer) Serve(conn net.PacketConn) error {
+	b := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(b)
+		if err != nil {
+			return err
+		}
+		req, err := dhcpv4.FromBytes(b[:n])
+		if err != nil {
+			continue
+		}
+		resp, err := s.Handle(req)
+		if err != nil || resp == nil {
+			continue
+		}
+		if ua, ok := peer.(*net.UDPAddr); ok && ua.IP.IsUnspecified() {
+			peer = &net.UDPAddr{IP: net.IPv4bcast, Port: dhcpv4.ClientPort}
+		}
+		if _, err := conn.WriteTo(resp.ToBytes(), peer); err != nil {
+			return err
+		}
+	}
+}
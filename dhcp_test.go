@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/pkg/errors"
+)
+
+func dhcpServer(t *testing.T) (*DhcpServer, *IpTree[string], *ManualClock) {
+	t.Helper()
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	ipam := New[string](WithClock(clock))
+	if err := ipam.AddPrefix("10.0.0.0/24", "net"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ipam.AddRange("10.0.0.10-10.0.0.11", "pool"); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewDhcpServer(ipam, nil, net.IPv4(10, 0, 0, 1), time.Hour, "10.0.0.10-10.0.0.11")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, ipam, clock
+}
+
+// acquire runs a discover and a request for the client with mac address
+// mac through the handler of the server and returns the ack
+func acquire(t *testing.T, s *DhcpServer, mac net.HardwareAddr) *dhcpv4.DHCPv4 {
+	t.Helper()
+	discover, err := dhcpv4.NewDiscovery(mac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offer, err := s.Handle(discover)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offer.MessageType() != dhcpv4.MessageTypeOffer {
+		t.Fatalf("discover returned %s", offer.MessageType())
+	}
+	request, err := dhcpv4.NewRequestFromOffer(offer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ack, err := s.Handle(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ack.MessageType() != dhcpv4.MessageTypeAck {
+		t.Fatalf("request returned %s", ack.MessageType())
+	}
+	return ack
+}
+
+func TestDhcpLeasesInTree(t *testing.T) {
+	s, ipam, _ := dhcpServer(t)
+	mac := net.HardwareAddr{0, 1, 2, 3, 4, 5}
+	ack := acquire(t, s, mac)
+	ip := ack.YourIPAddr.String()
+	if ip != "10.0.0.10" {
+		t.Errorf("leased %s, want 10.0.0.10", ip)
+	}
+	if d := ack.IPAddressLeaseTime(0); d != time.Hour {
+		t.Errorf("lease time %s, want %s", d, time.Hour)
+	}
+	leases := ipam.Leases()
+	if len(leases) != 1 || leases[0].IP != ip || leases[0].Owner != mac.String() || leases[0].TTL != time.Hour {
+		t.Fatalf("tree has leases %v", leases)
+	}
+	if v, _ := ipam.GetIP(ip); v != "dhcp "+mac.String() {
+		t.Errorf("%s has value %q", ip, v)
+	}
+
+	release, err := dhcpv4.NewReleaseFromACK(ack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Handle(release); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ipam.GetIP(ip); ok {
+		t.Errorf("%s is still allocated", ip)
+	}
+	if n := len(s.Leases()); n != 0 {
+		t.Errorf("server has %d leases", n)
+	}
+}
+
+// an expired lease is released by the reaper of the tree and its address
+// is leased again
+func TestDhcpExpiry(t *testing.T) {
+	s, ipam, clock := dhcpServer(t)
+	a := net.HardwareAddr{0, 1, 2, 3, 4, 5}
+	b := net.HardwareAddr{0, 1, 2, 3, 4, 6}
+	c := net.HardwareAddr{0, 1, 2, 3, 4, 7}
+	acquire(t, s, a)
+	acquire(t, s, b)
+
+	discover, err := dhcpv4.NewDiscovery(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Handle(discover); err == nil {
+		t.Fatal("exhausted pools made an offer")
+	}
+
+	clock.Advance(2 * time.Hour)
+	expired, err := ipam.ReapExpired()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expired) != 2 {
+		t.Fatalf("reaped %v", expired)
+	}
+	if ack := acquire(t, s, c); ack.YourIPAddr.String() != "10.0.0.10" {
+		t.Errorf("leased %s, want 10.0.0.10", ack.YourIPAddr)
+	}
+}
+
+// newInProcessDhcpClient serves dhcp on a loopback udp socket and returns
+// a client with mac address mac connected to it, the client and the server
+// are closed when the test ends
+func newInProcessDhcpClient(t *testing.T, s *DhcpServer, mac net.HardwareAddr) *dhcpClient {
+	t.Helper()
+	sconn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(sconn)
+	cconn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		sconn.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cconn.Close()
+		sconn.Close()
+	})
+	return &dhcpClient{
+		conn:   cconn,
+		server: sconn.LocalAddr(),
+		mac:    mac,
+	}
+}
+
+// dhcpClient is a minimal dhcpv4 client that runs over a packet connection
+// to a single server
+type dhcpClient struct {
+	conn   net.PacketConn
+	server net.Addr
+	mac    net.HardwareAddr
+	ack    *dhcpv4.DHCPv4
+}
+
+// Acquire runs a discover, offer, request, ack exchange and returns the ack
+func (c *dhcpClient) Acquire(ctx context.Context) (*dhcpv4.DHCPv4, error) {
+	discover, err := dhcpv4.NewDiscovery(c.mac)
+	if err != nil {
+		return nil, err
+	}
+	offer, err := c.exchange(ctx, discover, dhcpv4.MessageTypeOffer)
+	if err != nil {
+		return nil, err
+	}
+	request, err := dhcpv4.NewRequestFromOffer(offer)
+	if err != nil {
+		return nil, err
+	}
+	return c.request(ctx, request)
+}
+
+// Renew extends the lease of the last ack
+func (c *dhcpClient) Renew(ctx context.Context) (*dhcpv4.DHCPv4, error) {
+	if c.ack == nil {
+		return nil, errors.New("no lease to renew")
+	}
+	// a renewing client sends its address in ciaddr without a server
+	// identifier
+	request, err := dhcpv4.New(
+		dhcpv4.WithMessageType(dhcpv4.MessageTypeRequest),
+		dhcpv4.WithClientIP(c.ack.YourIPAddr),
+		dhcpv4.WithHwAddr(c.ack.ClientHWAddr),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return c.request(ctx, request)
+}
+
+// Release releases the lease of the last ack
+func (c *dhcpClient) Release() error {
+	if c.ack == nil {
+		return nil
+	}
+	release, err := dhcpv4.NewReleaseFromACK(c.ack)
+	if err != nil {
+		return err
+	}
+	c.ack = nil
+	_, err = c.conn.WriteTo(release.ToBytes(), c.server)
+	return err
+}
+
+func (c *dhcpClient) request(ctx context.Context, request *dhcpv4.DHCPv4) (*dhcpv4.DHCPv4, error) {
+	ack, err := c.exchange(ctx, request, dhcpv4.MessageTypeAck)
+	if err != nil {
+		return nil, err
+	}
+	c.ack = ack
+	return ack, nil
+}
+
+// exchange sends msg to the server and waits for the reply of type want
+func (c *dhcpClient) exchange(ctx context.Context, msg *dhcpv4.DHCPv4, want dhcpv4.MessageType) (*dhcpv4.DHCPv4, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetReadDeadline(deadline)
+	} else {
+		c.conn.SetReadDeadline(time.Time{})
+	}
+	if _, err := c.conn.WriteTo(msg.ToBytes(), c.server); err != nil {
+		return nil, err
+	}
+	b := make([]byte, 1500)
+	for {
+		n, _, err := c.conn.ReadFrom(b)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := dhcpv4.FromBytes(b[:n])
+		if err != nil || resp.TransactionID != msg.TransactionID {
+			continue
+		}
+		switch resp.MessageType() {
+		case want:
+			return resp, nil
+		case dhcpv4.MessageTypeNak:
+			return nil, errors.Errorf("request for %s refused: %s", msg.RequestedIPAddress(), resp.Message())
+		}
+	}
+}
+
+func TestDhcpClient(t *testing.T) {
+	s, ipam, _ := dhcpServer(t)
+	mac := net.HardwareAddr{0, 1, 2, 3, 4, 5}
+	c := newInProcessDhcpClient(t, s, mac)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ack, err := c.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ip := ack.YourIPAddr.String()
+	if ip != "10.0.0.10" {
+		t.Errorf("leased %s, want 10.0.0.10", ip)
+	}
+	renewed, err := c.Renew(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed.YourIPAddr.String() != ip {
+		t.Errorf("renewed %s, want %s", renewed.YourIPAddr, ip)
+	}
+	if err := c.Release(); err != nil {
+		t.Fatal(err)
+	}
+	// the release has no reply, the server handles it under its lock
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		s.l.Lock()
+		_, ok := ipam.GetIP(ip)
+		s.l.Unlock()
+		if !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s is still allocated after the release", ip)
+		}
+	}
+}
+
+// the server does not reap the leases of the tree, an expired lease is
+// released by the reaper
+func TestDhcpDoesNotReap(t *testing.T) {
+	s, ipam, clock := dhcpServer(t)
+	if err := ipam.ClaimIPWithLease("10.0.0.20", "other", "owner", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Hour)
+	acquire(t, s, net.HardwareAddr{0, 1, 2, 3, 4, 5})
+	if _, ok := ipam.GetIP("10.0.0.20"); !ok {
+		t.Error("a discover reaped a lease that is not a dhcp lease")
+	}
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"inet.af/netaddr"
+)
+
+const defaultZoneTTL = time.Hour
+
+// ZoneOptions are the parameters of the zones generated from the tree
+type ZoneOptions struct {
+	// Domain is the origin of the forward zone, relative hostnames are
+	// qualified with it
+	Domain string
+	// NS are the name servers of the zones, the first one is the primary
+	NS []string
+	// Hostmaster is the mailbox of the zone administrator in dns form,
+	// hostmaster.<domain> when not set
+	Hostmaster string
+	// TTL of the records, one hour when not set
+	TTL time.Duration
+	// Serial of the zones, the revision of the tree when not set such that
+	// the serial increases with every change of the tree
+	Serial uint32
+}
+
+// Record is a resource record of a zone, the name is relative to the
+// origin of the zone
+type Record struct {
+	Name string
+	Type string
+	Data string
+}
+
+// Zone is a dns zone generated from the allocations of the tree
+type Zone struct {
+	Origin  string
+	TTL     time.Duration
+	Serial  uint32
+	NS      []string
+	Mailbox string
+	Records []Record
+	// Delegation are the records that delegate a classless reverse zone
+	// (RFC 2317), they belong in the zone of the enclosing /24
+	Delegation []Record
+}
+
+// WriteTo writes the zone in zone file format
+func (z *Zone) WriteTo(w io.Writer) (int64, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "$ORIGIN %s\n", z.Origin)
+	fmt.Fprintf(&sb, "$TTL %d\n", int64(z.TTL.Seconds()))
+	fmt.Fprintf(&sb, "@\tIN\tSOA\t%s %s (%d %d %d %d %d)\n",
+		z.NS[0], z.Mailbox, z.Serial, 3600, 900, 1209600, int64(z.TTL.Seconds()))
+	for _, ns := range z.NS {
+		fmt.Fprintf(&sb, "@\tIN\tNS\t%s\n", ns)
+	}
+	for _, r := range z.Records {
+		fmt.Fprintf(&sb, "%s\tIN\t%s\t%s\n", r.Name, r.Type, r.Data)
+	}
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+// String returns the zone file of the zone
+func (z *Zone) String() string {
+	var sb strings.Builder
+	z.WriteTo(&sb)
+	return sb.String()
+}
+
+// ForwardZone returns the zone of the domain with the A and AAAA records of
+// the allocated ip addresses, hostname returns the name of an address from
+// its value and an empty name when the address has no record. Hostnames
+// outside of the domain are skipped
+func (ipam *IpTree[T]) ForwardZone(hostname func(T) string, o ZoneOptions) (*Zone, error) {
+	if o.Domain == "" {
+		return nil, errors.Wrap(ErrInvalid, "forward zone needs a domain")
+	}
+	z, err := ipam.newZone(fqdn(o.Domain), o)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range ipam.Export() {
+		ip, err := netaddr.ParseIP(e.Key)
+		if err != nil {
+			continue
+		}
+		name := hostname(e.Value)
+		if name == "" {
+			continue
+		}
+		name = qualify(name, o.Domain)
+		if name != z.Origin && !strings.HasSuffix(name, "."+z.Origin) {
+			continue
+		}
+		rtype := "A"
+		if ip.Is6() {
+			rtype = "AAAA"
+		}
+		z.Records = append(z.Records, Record{Name: relativeName(name, z.Origin), Type: rtype, Data: ip.String()})
+	}
+	sort.SliceStable(z.Records, func(i, j int) bool {
+		return z.Records[i].Name < z.Records[j].Name
+	})
+	return z, nil
+}
+
+// ReverseZones returns the in-addr.arpa or ip6.arpa zones of prefix p with
+// the PTR records of the allocated ip addresses in the prefix. A prefix
+// that is not on an octet (ipv4) or nibble (ipv6) boundary is split in the
+// zones of its more specific aligned prefixes, an ipv4 prefix longer than
+// /24 is a classless zone as in RFC 2317 with the delegation records for
+// the enclosing zone. The zone of a /24 delegates the prefixes longer than
+// /24 that are stored in the tree instead of holding their records
+func (ipam *IpTree[T]) ReverseZones(p string, hostname func(T) string, o ZoneOptions) ([]*Zone, error) {
+	p, _, err := Canonicalize(p)
+	if err != nil {
+		return nil, err
+	}
+	pfx, err := netaddr.ParseIPPrefix(p)
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalid, "cannot parse prefix %s: %v", p, err)
+	}
+
+	names := map[netaddr.IP]string{}
+	ips := []netaddr.IP{}
+	// ipv4 prefixes longer than /24 in the tree are classless zones that
+	// are delegated by the zone of their /24
+	classlessPrefixes := []netaddr.IPPrefix{}
+	for _, e := range ipam.Export() {
+		if cp, err := netaddr.ParseIPPrefix(e.Key); err == nil {
+			if cp.IP().Is4() && cp.Bits() > 24 && cp.Bits() > pfx.Bits() && pfx.Contains(cp.IP()) {
+				classlessPrefixes = append(classlessPrefixes, cp)
+			}
+			continue
+		}
+		ip, err := netaddr.ParseIP(e.Key)
+		if err != nil || !pfx.Contains(ip) {
+			continue
+		}
+		if name := hostname(e.Value); name != "" {
+			names[ip] = qualify(name, o.Domain)
+			ips = append(ips, ip)
+		}
+	}
+	sort.Slice(ips, func(i, j int) bool {
+		return ips[i].Less(ips[j])
+	})
+
+	zones := []*Zone{}
+	for _, zp := range zonePrefixes(pfx) {
+		origin, classless := reverseOrigin(zp)
+		z, err := ipam.newZone(origin, o)
+		if err != nil {
+			return nil, err
+		}
+		delegated := []netaddr.IPPrefix{}
+		if zp.IP().Is4() && zp.Bits() == 24 {
+			for _, cp := range outermostPrefixes(classlessPrefixes) {
+				if zp.Contains(cp.IP()) {
+					delegated = append(delegated, cp)
+					z.Records = append(z.Records, classlessDelegation(cp, z.NS)...)
+				}
+			}
+		}
+	ips:
+		for _, ip := range ips {
+			if !zp.Contains(ip) {
+				continue
+			}
+			for _, cp := range delegated {
+				if cp.Contains(ip) {
+					continue ips
+				}
+			}
+			name := relativeName(reverseName(ip), origin)
+			if classless {
+				name = fmt.Sprint(ip.As4()[3])
+			}
+			z.Records = append(z.Records, Record{Name: name, Type: "PTR", Data: names[ip]})
+		}
+		if classless {
+			z.Delegation = classlessDelegation(zp, z.NS)
+		}
+		zones = append(zones, z)
+	}
+	return zones, nil
+}
+
+// classlessDelegation returns the NS records of the classless zone of
+// prefix p and a CNAME for every address of the prefix, also the ones
+// without a record, as they are written in the zone of the enclosing /24
+func classlessDelegation(p netaddr.IPPrefix, ns []string) []Record {
+	origin, _ := reverseOrigin(p)
+	label := strings.TrimSuffix(origin, "."+reverseOrigin24(p))
+	records := []Record{}
+	for _, n := range ns {
+		records = append(records, Record{Name: label, Type: "NS", Data: n})
+	}
+	r := p.Range()
+	for ip := r.From(); ; ip = ip.Next() {
+		octet := fmt.Sprint(ip.As4()[3])
+		records = append(records, Record{Name: octet, Type: "CNAME", Data: octet + "." + origin})
+		if ip == r.To() {
+			break
+		}
+	}
+	return records
+}
+
+// outermostPrefixes returns the prefixes that are not contained in another
+// prefix of the list sorted by address
+func outermostPrefixes(prefixes []netaddr.IPPrefix) []netaddr.IPPrefix {
+	sort.Slice(prefixes, func(i, j int) bool {
+		if prefixes[i].IP() != prefixes[j].IP() {
+			return prefixes[i].IP().Less(prefixes[j].IP())
+		}
+		return prefixes[i].Bits() < prefixes[j].Bits()
+	})
+	outermost := []netaddr.IPPrefix{}
+	for _, p := range prefixes {
+		if n := len(outermost); n > 0 && outermost[n-1].Contains(p.IP()) {
+			continue
+		}
+		outermost = append(outermost, p)
+	}
+	return outermost
+}
+
+// newZone returns an empty zone with the options applied
+func (ipam *IpTree[T]) newZone(origin string, o ZoneOptions) (*Zone, error) {
+	if len(o.NS) == 0 {
+		return nil, errors.Wrap(ErrInvalid, "zone needs a name server")
+	}
+	z := &Zone{
+		Origin:  origin,
+		TTL:     o.TTL,
+		Serial:  o.Serial,
+		Mailbox: o.Hostmaster,
+		Records: []Record{},
+	}
+	if z.TTL <= 0 {
+		z.TTL = defaultZoneTTL
+	}
+	if z.Serial == 0 {
+		z.Serial = uint32(ipam.Revision())
+	}
+	for _, ns := range o.NS {
+		z.NS = append(z.NS, qualify(ns, o.Domain))
+	}
+	switch {
+	case z.Mailbox != "":
+		z.Mailbox = qualify(strings.Replace(z.Mailbox, "@", ".", 1), o.Domain)
+	case o.Domain != "":
+		z.Mailbox = "hostmaster." + fqdn(o.Domain)
+	default:
+		z.Mailbox = "hostmaster." + origin
+	}
+	return z, nil
+}
+
+// zonePrefixes returns the prefixes of the reverse zones of prefix p
+func zonePrefixes(p netaddr.IPPrefix) []netaddr.IPPrefix {
+	step := uint8(4)
+	if p.IP().Is4() {
+		if p.Bits() > 24 {
+			return []netaddr.IPPrefix{p}
+		}
+		step = 8
+	}
+	bits := (p.Bits() + step - 1) / step * step
+	if bits == p.Bits() {
+		return []netaddr.IPPrefix{p}
+	}
+	prefixes := []netaddr.IPPrefix{}
+	for zp := netaddr.IPPrefixFrom(p.IP(), bits); p.Contains(zp.IP()); {
+		prefixes = append(prefixes, zp)
+		next := zp.Range().To().Next()
+		if next.IsZero() {
+			break
+		}
+		zp = netaddr.IPPrefixFrom(next, bits)
+	}
+	return prefixes
+}
+
+// reverseOrigin returns the origin of the reverse zone of the aligned
+// prefix p and whether it is a classless zone
+func reverseOrigin(p netaddr.IPPrefix) (string, bool) {
+	if p.IP().Is4() {
+		if p.Bits() > 24 {
+			return fmt.Sprintf("%d/%d.%s", p.IP().As4()[3], p.Bits(), reverseOrigin24(p)), true
+		}
+		labels := strings.Split(reverseName(p.IP()), ".")
+		return strings.Join(labels[4-p.Bits()/8:], "."), false
+	}
+	labels := strings.Split(reverseName(p.IP()), ".")
+	return strings.Join(labels[32-p.Bits()/4:], "."), false
+}
+
+// reverseOrigin24 returns the origin of the reverse zone of the /24 that
+// holds the ipv4 prefix p
+func reverseOrigin24(p netaddr.IPPrefix) string {
+	a := p.IP().As4()
+	return fmt.Sprintf("%d.%d.%d.in-addr.arpa.", a[2], a[1], a[0])
+}
+
+// reverseName returns the PTR name of ip address ip
+func reverseName(ip netaddr.IP) string {
+	labels := []string{}
+	if ip.Is4() {
+		a := ip.As4()
+		for i := 3; i >= 0; i-- {
+			labels = append(labels, fmt.Sprint(a[i]))
+		}
+		return strings.Join(labels, ".") + ".in-addr.arpa."
+	}
+	a := ip.As16()
+	for i := 15; i >= 0; i-- {
+		labels = append(labels, fmt.Sprintf("%x", a[i]&0xf), fmt.Sprintf("%x", a[i]>>4))
+	}
+	return strings.Join(labels, ".") + ".ip6.arpa."
+}
+
+// relativeName returns name relative to origin, @ for the origin itself
+func relativeName(name, origin string) string {
+	if name == origin {
+		return "@"
+	}
+	return strings.TrimSuffix(name, "."+origin)
+}
+
+// qualify returns name as a fully qualified domain name, a relative name
+// is qualified with domain
+func qualify(name, domain string) string {
+	if strings.HasSuffix(name, ".") || domain == "" {
+		return fqdn(name)
+	}
+	return name + "." + fqdn(domain)
+}
+
+func fqdn(name string) string {
+	return strings.TrimSuffix(name, ".") + "."
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// dnsTree returns a tree with allocated ipv4 and ipv6 addresses whose
+// values are their hostnames, 10.0.0.64/30 is a classless reverse zone
+func dnsTree(t *testing.T) *IpTree[string] {
+	t.Helper()
+	ipam := New[string]()
+	for _, p := range []string{"10.0.0.0/24", "10.0.0.64/30", "2001:db8::/64"} {
+		if err := ipam.AddPrefix(p, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for ip, name := range map[string]string{
+		"10.0.0.5":    "web",
+		"10.0.0.6":    "db.example.org.",
+		"10.0.0.7":    "",
+		"10.0.0.65":   "gw",
+		"2001:db8::1": "web",
+	} {
+		if err := ipam.ClaimIP(ip, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ipam
+}
+
+var dnsOptions = ZoneOptions{Domain: "example.com", NS: []string{"ns1"}}
+
+func hostname(v string) string {
+	return v
+}
+
+func TestForwardZone(t *testing.T) {
+	z, err := dnsTree(t).ForwardZone(hostname, dnsOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// db.example.org. is outside of the domain and 10.0.0.7 has no name
+	want := []Record{
+		{Name: "gw", Type: "A", Data: "10.0.0.65"},
+		{Name: "web", Type: "A", Data: "10.0.0.5"},
+		{Name: "web", Type: "AAAA", Data: "2001:db8::1"},
+	}
+	if !reflect.DeepEqual(z.Records, want) {
+		t.Errorf("records %v, want %v", z.Records, want)
+	}
+	if z.Origin != "example.com." || !reflect.DeepEqual(z.NS, []string{"ns1.example.com."}) || z.Mailbox != "hostmaster.example.com." {
+		t.Errorf("zone %s with name servers %v and mailbox %s", z.Origin, z.NS, z.Mailbox)
+	}
+	if _, err := New[string]().ForwardZone(hostname, ZoneOptions{NS: []string{"ns1"}}); err == nil {
+		t.Error("forward zone without a domain")
+	}
+}
+
+func TestReverseZoneOrigins(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		origins []string
+	}{
+		{"10.0.0.0/16", []string{"0.10.in-addr.arpa."}},
+		{"10.0.0.0/23", []string{"0.0.10.in-addr.arpa.", "1.0.10.in-addr.arpa."}},
+		{"10.0.0.0/15", []string{"0.10.in-addr.arpa.", "1.10.in-addr.arpa."}},
+		{"10.0.0.128/25", []string{"128/25.0.0.10.in-addr.arpa."}},
+		{"2001:db8::/48", []string{"0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."}},
+		{"2001:db8::/31", []string{"8.b.d.0.1.0.0.2.ip6.arpa.", "9.b.d.0.1.0.0.2.ip6.arpa."}},
+		{"2001:db8::/46", []string{"0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "1.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "2.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "3.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."}},
+	}
+	for _, tt := range tests {
+		zones, err := New[string]().ReverseZones(tt.prefix, hostname, dnsOptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		origins := []string{}
+		for _, z := range zones {
+			origins = append(origins, z.Origin)
+		}
+		if !reflect.DeepEqual(origins, tt.origins) {
+			t.Errorf("zones of %s are %v, want %v", tt.prefix, origins, tt.origins)
+		}
+	}
+}
+
+func TestReverseZones(t *testing.T) {
+	// the records of the classless zone are delegated by the zone of its /24
+	delegation := []Record{
+		{Name: "64/30", Type: "NS", Data: "ns1.example.com."},
+		{Name: "64", Type: "CNAME", Data: "64.64/30.0.0.10.in-addr.arpa."},
+		{Name: "65", Type: "CNAME", Data: "65.64/30.0.0.10.in-addr.arpa."},
+		{Name: "66", Type: "CNAME", Data: "66.64/30.0.0.10.in-addr.arpa."},
+		{Name: "67", Type: "CNAME", Data: "67.64/30.0.0.10.in-addr.arpa."},
+	}
+	tests := []struct {
+		prefix     string
+		origin     string
+		records    []Record
+		delegation []Record
+	}{
+		{
+			prefix: "10.0.0.0/24",
+			origin: "0.0.10.in-addr.arpa.",
+			records: append(append([]Record{}, delegation...),
+				Record{Name: "5", Type: "PTR", Data: "web.example.com."},
+				Record{Name: "6", Type: "PTR", Data: "db.example.org."},
+			),
+		},
+		{
+			prefix:     "10.0.0.64/30",
+			origin:     "64/30.0.0.10.in-addr.arpa.",
+			records:    []Record{{Name: "65", Type: "PTR", Data: "gw.example.com."}},
+			delegation: delegation,
+		},
+		{
+			prefix:  "2001:db8::/64",
+			origin:  "0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+			records: []Record{{Name: "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0", Type: "PTR", Data: "web.example.com."}},
+		},
+	}
+	ipam := dnsTree(t)
+	for _, tt := range tests {
+		zones, err := ipam.ReverseZones(tt.prefix, hostname, dnsOptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(zones) != 1 {
+			t.Fatalf("%s has %d zones", tt.prefix, len(zones))
+		}
+		z := zones[0]
+		if z.Origin != tt.origin {
+			t.Errorf("%s has origin %s, want %s", tt.prefix, z.Origin, tt.origin)
+		}
+		if !reflect.DeepEqual(z.Records, tt.records) {
+			t.Errorf("%s has records %v, want %v", tt.prefix, z.Records, tt.records)
+		}
+		if len(z.Delegation) != 0 || len(tt.delegation) != 0 {
+			if !reflect.DeepEqual(z.Delegation, tt.delegation) {
+				t.Errorf("%s has delegation %v, want %v", tt.prefix, z.Delegation, tt.delegation)
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"inet.af/netaddr"
+)
+
+var (
+	// ErrMixedFamily is returned when a range starts and ends in a different
+	// address family
+	ErrMixedFamily = errors.New("mixed ipv4/ipv6 address family")
+	// ErrInvalid is returned when a prefix, range or ip address cannot be
+	// parsed
+	ErrInvalid = errors.New("invalid prefix, range or ip address")
+)
+
+// Family is the address family of a prefix, range or ip address
+type Family int
+
+const (
+	FamilyIPv4 Family = iota
+	FamilyIPv6
+)
+
+// Families lists the address families in the order they are walked
+var Families = []Family{FamilyIPv4, FamilyIPv6}
+
+func (f Family) String() string {
+	switch f {
+	case FamilyIPv4:
+		return "ipv4"
+	case FamilyIPv6:
+		return "ipv6"
+	}
+	return "unknown"
+}
+
+func familyOfIP(ip netaddr.IP) Family {
+	if ip.Is4() {
+		return FamilyIPv4
+	}
+	return FamilyIPv6
+}
+
+// canonicalPrefix returns an ipv4-mapped ipv6 prefix (::ffff:10.0.0.0/104)
+// as the ipv4 prefix (10.0.0.0/8), other prefixes are returned masked
+func canonicalPrefix(p netaddr.IPPrefix) netaddr.IPPrefix {
+	if p.IP().Is4in6() && p.Bits() >= 96 {
+		p = netaddr.IPPrefixFrom(p.IP().Unmap(), p.Bits()-96)
+	}
+	return p.Masked()
+}
+
+// canonicalRange returns an ipv4-mapped ipv6 range as an ipv4 range, a
+// range with a different start and end family is rejected
+func canonicalRange(r netaddr.IPRange) (netaddr.IPRange, error) {
+	from := r.From().Unmap()
+	to := r.To().Unmap()
+	if from.Is4() != to.Is4() {
+		return netaddr.IPRange{}, errors.Wrapf(ErrMixedFamily, "invalid IP Range: %s-%s", r.From(), r.To())
+	}
+	ra := netaddr.IPRangeFrom(from, to)
+	if from.IsZero() || to.Less(from) {
+		return netaddr.IPRange{}, errors.Errorf("invalid IP Range: %s-%s", r.From(), r.To())
+	}
+	return ra, nil
+}
+
+// Canonicalize parses a prefix, range or ip address and returns it in its
+// canonical string form together with its address family
+func Canonicalize(s string) (string, Family, error) {
+	switch {
+	case strings.Contains(s, "-"):
+		r, err := ParseRange(s)
+		if err != nil {
+			return "", 0, err
+		}
+		return r.String(), familyOfIP(r.From()), nil
+	case strings.Contains(s, "/"):
+		p, err := netaddr.ParseIPPrefix(s)
+		if err != nil {
+			return "", 0, errors.Wrapf(ErrInvalid, "cannot parse prefix %s: %v", s, err)
+		}
+		p = canonicalPrefix(p)
+		return p.String(), familyOfIP(p.IP()), nil
+	default:
+		ip, err := netaddr.ParseIP(s)
+		if err != nil {
+			return "", 0, errors.Wrapf(ErrInvalid, "cannot parse ip address %s: %v", s, err)
+		}
+		ip = ip.Unmap()
+		return ip.String(), familyOfIP(ip), nil
+	}
+}
+
+// ParsePrefix parses a prefix and returns it in its canonical form, it is
+// the edge between the string and the netaddr api of the tree
+func ParsePrefix(s string) (netaddr.IPPrefix, error) {
+	p, err := netaddr.ParseIPPrefix(s)
+	if err != nil {
+		return netaddr.IPPrefix{}, errors.Wrapf(ErrInvalid, "cannot parse prefix %s: %v", s, err)
+	}
+	return canonicalPrefix(p), nil
+}
+
+// ParseRange parses a range and returns it in its canonical form
+func ParseRange(s string) (netaddr.IPRange, error) {
+	r, err := netaddr.ParseIPRange(s)
+	if err != nil {
+		// ParseIPRange rejects a range that mixes an ipv4 and an ipv4-mapped
+		// address or mixes families, let canonicalRange sort them out
+		from, to, found := strings.Cut(s, "-")
+		if !found {
+			return netaddr.IPRange{}, errors.Wrapf(ErrInvalid, "cannot parse range %s: %v", s, err)
+		}
+		f, errf := netaddr.ParseIP(from)
+		t, errt := netaddr.ParseIP(to)
+		if errf != nil || errt != nil {
+			return netaddr.IPRange{}, errors.Wrapf(ErrInvalid, "cannot parse range %s: %v", s, err)
+		}
+		r = netaddr.IPRangeFrom(f, t)
+	}
+	return canonicalRange(r)
+}
+
+// keyRange returns the addresses of the prefix, range or ip address key
+func keyRange(k string) (netaddr.IPRange, error) {
+	switch {
+	case strings.Contains(k, "-"):
+		return netaddr.ParseIPRange(k)
+	case strings.Contains(k, "/"):
+		p, err := netaddr.ParseIPPrefix(k)
+		if err != nil {
+			return netaddr.IPRange{}, err
+		}
+		return p.Range(), nil
+	default:
+		ip, err := netaddr.ParseIP(k)
+		if err != nil {
+			return netaddr.IPRange{}, err
+		}
+		return netaddr.IPRangeFrom(ip, ip), nil
+	}
+}
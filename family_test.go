@@ -0,0 +1,164 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		want   string
+		family Family
+		err    error
+	}{
+		{name: "ipv4 prefix", in: "10.0.3.1/24", want: "10.0.3.0/24", family: FamilyIPv4},
+		{name: "ipv6 prefix", in: "2001:db8::1/64", want: "2001:db8::/64", family: FamilyIPv6},
+		{name: "4in6 prefix", in: "::ffff:10.0.3.0/120", want: "10.0.3.0/24", family: FamilyIPv4},
+		{name: "4in6 prefix is masked", in: "::ffff:10.0.3.7/104", want: "10.0.0.0/8", family: FamilyIPv4},
+		{name: "short 4in6 prefix stays ipv6", in: "::ffff:0.0.0.0/80", want: "::/80", family: FamilyIPv6},
+		{name: "ipv4 range", in: "10.0.0.10-10.0.0.20", want: "10.0.0.10-10.0.0.20", family: FamilyIPv4},
+		{name: "4in6 range", in: "::ffff:10.0.0.10-::ffff:10.0.0.20", want: "10.0.0.10-10.0.0.20", family: FamilyIPv4},
+		{name: "half 4in6 range", in: "10.0.0.10-::ffff:10.0.0.20", want: "10.0.0.10-10.0.0.20", family: FamilyIPv4},
+		{name: "ipv6 range", in: "2001:db8::1-2001:db8::ff", want: "2001:db8::1-2001:db8::ff", family: FamilyIPv6},
+		{name: "ipv4 address", in: "10.0.0.1", want: "10.0.0.1", family: FamilyIPv4},
+		{name: "4in6 address", in: "::ffff:10.0.0.1", want: "10.0.0.1", family: FamilyIPv4},
+		{name: "ipv6 address", in: "2001:db8::1", want: "2001:db8::1", family: FamilyIPv6},
+		{name: "mixed range", in: "10.0.0.1-2001:db8::1", err: ErrMixedFamily},
+		{name: "mixed range from ipv6", in: "2001:db8::1-10.0.0.1", err: ErrMixedFamily},
+		{name: "invalid prefix", in: "10.0.0.0/33", err: ErrInvalid},
+		{name: "invalid range", in: "10.0.0.1-foo", err: ErrInvalid},
+		{name: "invalid address", in: "foo", err: ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, family, err := Canonicalize(tt.in)
+			if tt.err != nil {
+				if errors.Cause(err) != tt.err {
+					t.Fatalf("Canonicalize(%s) error = %v, want %v", tt.in, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || family != tt.family {
+				t.Errorf("Canonicalize(%s) = %s %s, want %s %s", tt.in, got, family, tt.want, tt.family)
+			}
+		})
+	}
+}
+
+func TestParseFamily(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+		err  error
+	}{
+		{name: "4in6 prefix", in: "::ffff:10.0.3.0/120", want: "10.0.3.0/24"},
+		{name: "4in6 range", in: "::ffff:10.0.0.10-::ffff:10.0.0.20", want: "10.0.0.10-10.0.0.20"},
+		{name: "mixed range", in: "10.0.0.1-2001:db8::1", err: ErrMixedFamily},
+		{name: "invalid prefix", in: "10.0.0.0/33", err: ErrInvalid},
+		{name: "invalid range", in: "10.0.0.1-foo", err: ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			var err error
+			if strings.Contains(tt.in, "-") {
+				r, rerr := ParseRange(tt.in)
+				got, err = r.String(), rerr
+			} else {
+				p, perr := ParsePrefix(tt.in)
+				got, err = p.String(), perr
+			}
+			if tt.err != nil {
+				if errors.Cause(err) != tt.err {
+					t.Fatalf("parse %s error = %v, want %v", tt.in, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("parse %s = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFamilyAddRejectsMixedRange(t *testing.T) {
+	ipam := New[string]()
+	if err := ipam.AddPrefix("::ffff:10.0.0.0/104", "net"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ipam.AddRange("10.0.0.1-2001:db8::1", "range"); errors.Cause(err) != ErrMixedFamily {
+		t.Fatalf("AddRange() error = %v, want %v", err, ErrMixedFamily)
+	}
+	if got, want := exportKeys(ipam), []string{"10.0.0.0/8"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keys = %v, want %v", got, want)
+	}
+}
+
+func TestFamilyStats(t *testing.T) {
+	ipam := New[string]()
+	for _, p := range []string{"10.0.0.0/8", "::ffff:10.1.0.0/112", "2001:db8::/32"} {
+		if err := ipam.AddPrefix(p, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, r := range []string{"10.0.0.10-10.0.0.20", "2001:db8::10-2001:db8::20", "2001:db8::30-2001:db8::40"} {
+		if err := ipam.AddRange(r, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, ip := range []string{"::ffff:10.2.0.1", "10.2.0.2", "2001:db8::1"} {
+		if err := ipam.ClaimIP(ip, ip); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		family Family
+		keys   []string
+		stats  Stats
+	}{
+		{
+			family: FamilyIPv4,
+			keys:   []string{"10.0.0.0/8", "10.0.0.10-10.0.0.20", "10.1.0.0/16", "10.2.0.1", "10.2.0.2"},
+			stats:  Stats{Prefixes: 2, Ranges: 1, Addresses: 2},
+		},
+		{
+			family: FamilyIPv6,
+			keys:   []string{"2001:db8::/32", "2001:db8::1", "2001:db8::10-2001:db8::20", "2001:db8::30-2001:db8::40"},
+			stats:  Stats{Prefixes: 1, Ranges: 2, Addresses: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.family.String(), func(t *testing.T) {
+			keys := []string{}
+			for _, e := range ipam.ExportFamily(tt.family) {
+				keys = append(keys, e.Key)
+			}
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, tt.keys) {
+				t.Errorf("ExportFamily(%s) = %v, want %v", tt.family, keys, tt.keys)
+			}
+			stats := ipam.Stats(tt.family)
+			if stats.Nodes == 0 {
+				t.Errorf("Stats(%s).Nodes = 0, want the nodes of the tree", tt.family)
+			}
+			stats.Nodes = 0
+			if stats != tt.stats {
+				t.Errorf("Stats(%s) = %+v, want %+v", tt.family, stats, tt.stats)
+			}
+		})
+	}
+}
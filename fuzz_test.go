@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/pkg/errors"
+	"inet.af/netaddr"
+)
+
+// fuzzSteps is the amount of random changes applied to a tree by FuzzTree
+const fuzzSteps = 300
+
+// FuzzTree applies a random sequence of prefix and range additions and
+// deletions of both address families to a tree with the policy flags in the
+// bits of policy. Every change is pre-checked first and the pre-check has
+// to agree with the outcome of the change, the invariants of the tree are
+// checked after every step. The seed corpus holds every combination of the
+// policy flags
+func FuzzTree(f *testing.F) {
+	for i := 0; i < 16; i++ {
+		f.Add(uint8(i), int64(i+1))
+	}
+	f.Fuzz(func(t *testing.T, policy uint8, seed int64) {
+		p := Policy{
+			AllowNestedRanges:       policy&1 != 0,
+			AllowRangeWithoutParent: policy&2 != 0,
+			AllowPrefixInRange:      policy&4 != 0,
+			AllowPrefixEqualRange:   policy&8 != 0,
+		}
+		if err := fuzzTree(p, seed, fuzzSteps); err != nil {
+			t.Fatalf("policy %+v, seed %d: %v", p, seed, err)
+		}
+	})
+}
+
+// fuzzTree applies steps random changes to a tree with policy p and returns
+// the first difference between a pre-check and its change or the first
+// violated invariant
+func fuzzTree(p Policy, seed int64, steps int) error {
+	r := rand.New(rand.NewSource(seed))
+	ipam := New[string](WithPolicy(p))
+	for i := 0; i < steps; i++ {
+		f := Families[r.Intn(len(Families))]
+		var err error
+		switch op := r.Intn(6); {
+		case op < 2:
+			err = ipam.fuzzAdd(randomPrefix(r, f).String(), fmt.Sprint(i))
+		case op < 4:
+			err = ipam.fuzzAdd(randomRange(r, f).String(), fmt.Sprint(i))
+		default:
+			// the tree holds prefixes and ranges only
+			entries := ipam.ExportFamily(f)
+			if len(entries) == 0 {
+				continue
+			}
+			err = ipam.fuzzDelete(entries[r.Intn(len(entries))].Key)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "step %d", i)
+		}
+		if err := ipam.CheckInvariants(); err != nil {
+			return errors.Wrapf(err, "step %d", i)
+		}
+	}
+	return nil
+}
+
+// randomPrefix returns a prefix from a small address space such that the
+// prefixes nest and collide
+func randomPrefix(r *rand.Rand, f Family) netaddr.IPPrefix {
+	if f == FamilyIPv4 {
+		ip := netaddr.IPv4(10, byte(r.Intn(4)), byte(r.Intn(4)), byte(r.Intn(256)))
+		p, _ := ip.Prefix(uint8(r.Intn(33)))
+		return p
+	}
+	a := [16]byte{0x20, 0x01, 0x0d, 0xb8}
+	a[6] = byte(r.Intn(4))
+	a[15] = byte(r.Intn(256))
+	p, _ := netaddr.IPFrom16(a).Prefix(uint8(r.Intn(129)))
+	return p
+}
+
+// randomRange returns a range from the address space of randomPrefix such
+// that ranges overlap and nest with each other and with the prefixes
+func randomRange(r *rand.Rand, f Family) netaddr.IPRange {
+	from := randomPrefix(r, f).IP()
+	to := from
+	for n := r.Intn(512); n > 0 && !to.Next().IsZero(); n-- {
+		to = to.Next()
+	}
+	return netaddr.IPRangeFrom(from, to)
+}
+
+// fuzzAdd pre-checks the addition of the prefix or range s and adds it
+func (ipam *IpTree[T]) fuzzAdd(s string, value T) error {
+	ok, err := ipam.PreCheckAddition(s)
+	if err != nil && !IsConflict(err) {
+		return errors.Wrapf(err, "pre-check add %s", s)
+	}
+	var addErr error
+	if _, ferr := ParseRange(s); ferr == nil {
+		addErr = ipam.AddRange(s, value)
+	} else {
+		addErr = ipam.AddPrefix(s, value)
+	}
+	if addErr != nil && !IsConflict(addErr) {
+		return errors.Wrapf(addErr, "add %s", s)
+	}
+	if ok != (addErr == nil) {
+		return errors.Wrapf(ErrInvariant, "pre-check add %s returned %t (%v), add returned %v", s, ok, err, addErr)
+	}
+	return nil
+}
+
+// fuzzDelete pre-checks the deletion of the prefix or range s and deletes
+// it, a prefix is deleted in strict mode
+func (ipam *IpTree[T]) fuzzDelete(s string) error {
+	ok, err := ipam.PreCheckDeletion(s)
+	if err != nil && !IsConflict(err) {
+		return errors.Wrapf(err, "pre-check delete %s", s)
+	}
+	var delErr error
+	if _, ferr := ParseRange(s); ferr == nil {
+		delErr = ipam.DeleteRange(s)
+	} else {
+		_, delErr = ipam.DeletePrefixWithOptions(s, DeleteOptions{Mode: DeleteStrict})
+	}
+	if delErr != nil && !IsConflict(delErr) {
+		return errors.Wrapf(delErr, "delete %s", s)
+	}
+	if ok != (delErr == nil) {
+		return errors.Wrapf(ErrInvariant, "pre-check delete %s returned %t (%v), delete returned %v", s, ok, err, delErr)
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/henderiw/lpm/ipampb"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GrpcServer exposes an ipam tree over grpc, the calls are serialized
+// since the tree is not safe for concurrent use
+type GrpcServer struct {
+	ipampb.UnimplementedIpamServer
+	m    sync.Mutex
+	ipam *IpTree[string]
+}
+
+func NewGrpcServer(ipam *IpTree[string]) *GrpcServer {
+	return &GrpcServer{
+		ipam: ipam,
+	}
+}
+
+// Register registers the ipam service on the grpc server g
+func (s *GrpcServer) Register(g *grpc.Server) {
+	ipampb.RegisterIpamServer(g, s)
+}
+
+// Serve serves the ipam service on the listener until it fails
+func (s *GrpcServer) Serve(l net.Listener) error {
+	g := grpc.NewServer()
+	s.Register(g)
+	return g.Serve(l)
+}
+
+// Locker returns the lock that serializes the calls, another server of the
+// tree such as the dhcp server takes it to share the tree
+func (s *GrpcServer) Locker() sync.Locker {
+	return &s.m
+}
+
+// tree returns the view of the tree for the actor and reason of the
+// x-actor and x-reason metadata of the call, they are recorded in the
+// audit log
+func (s *GrpcServer) tree(ctx context.Context) *IpTree[string] {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(k string) string {
+		if v := md.Get(k); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	return s.ipam.As(first("x-actor"), first("x-reason"))
+}
+
+func (s *GrpcServer) AddPrefix(ctx context.Context, req *ipampb.AddPrefixRequest) (*ipampb.AddPrefixResponse, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if !strings.Contains(req.GetPrefix(), "/") {
+		return nil, status.Errorf(codes.InvalidArgument, "%s is not a prefix", req.GetPrefix())
+	}
+	p, _, err := Canonicalize(req.GetPrefix())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if ok, err := s.ipam.PreCheckAddition(p); !ok {
+		return nil, grpcError(err)
+	}
+	if err := s.tree(ctx).AddPrefix(p, req.GetValue()); err != nil {
+		return nil, grpcError(err)
+	}
+	return &ipampb.AddPrefixResponse{Entry: grpcEntry(p, req.GetValue())}, nil
+}
+
+func (s *GrpcServer) GetPrefix(ctx context.Context, req *ipampb.GetPrefixRequest) (*ipampb.Entry, error) {
+	p, _, err := Canonicalize(req.GetPrefix())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if !strings.Contains(p, "/") {
+		return nil, status.Errorf(codes.InvalidArgument, "%s is not a prefix", p)
+	}
+	return s.Lookup(ctx, &ipampb.LookupRequest{Key: p})
+}
+
+func (s *GrpcServer) DeletePrefix(ctx context.Context, req *ipampb.DeletePrefixRequest) (*ipampb.DeletePrefixResponse, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	o := DeleteOptions{
+		DryRun: req.GetDryRun(),
+	}
+	switch req.GetMode() {
+	case ipampb.DeleteMode_DELETE_MODE_UNSPECIFIED, ipampb.DeleteMode_DELETE_MODE_REPARENT:
+		o.Mode = DeleteReparent
+	case ipampb.DeleteMode_DELETE_MODE_STRICT:
+		o.Mode = DeleteStrict
+	case ipampb.DeleteMode_DELETE_MODE_CASCADE:
+		o.Mode = DeleteCascade
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown delete mode %s", req.GetMode())
+	}
+	result, err := s.tree(ctx).DeletePrefixWithOptions(req.GetPrefix(), o)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &ipampb.DeletePrefixResponse{
+		Deleted:    result.Deleted,
+		Reparented: result.Reparented,
+		Parent:     result.Parent,
+	}, nil
+}
+
+func (s *GrpcServer) AddRange(ctx context.Context, req *ipampb.AddRangeRequest) (*ipampb.AddRangeResponse, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	ra, _, err := Canonicalize(req.GetRange())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if !strings.Contains(ra, "-") {
+		return nil, status.Errorf(codes.InvalidArgument, "%s is not a range", ra)
+	}
+	if ok, err := s.ipam.PreCheckAddition(ra); !ok {
+		return nil, grpcError(err)
+	}
+	if err := s.tree(ctx).AddRange(ra, req.GetValue()); err != nil {
+		return nil, grpcError(err)
+	}
+	return &ipampb.AddRangeResponse{Entry: grpcEntry(ra, req.GetValue())}, nil
+}
+
+func (s *GrpcServer) GetRange(ctx context.Context, req *ipampb.GetRangeRequest) (*ipampb.Entry, error) {
+	ra, _, err := Canonicalize(req.GetRange())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if !strings.Contains(ra, "-") {
+		return nil, status.Errorf(codes.InvalidArgument, "%s is not a range", ra)
+	}
+	return s.Lookup(ctx, &ipampb.LookupRequest{Key: ra})
+}
+
+func (s *GrpcServer) DeleteRange(ctx context.Context, req *ipampb.DeleteRangeRequest) (*ipampb.DeleteRangeResponse, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	ra, _, err := Canonicalize(req.GetRange())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if _, ok := s.ipam.GetRange(ra); !ok {
+		return nil, grpcError(errors.Wrapf(ErrNotFound, "range %s", ra))
+	}
+	if err := s.tree(ctx).DeleteRange(ra); err != nil {
+		return nil, grpcError(err)
+	}
+	return &ipampb.DeleteRangeResponse{}, nil
+}
+
+func (s *GrpcServer) Allocate(ctx context.Context, req *ipampb.AllocateRequest) (*ipampb.AllocateResponse, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if req.GetAddress() != "" {
+		ip, _, err := Canonicalize(req.GetAddress())
+		if err != nil {
+			return nil, grpcError(err)
+		}
+		if err := s.tree(ctx).ClaimIP(ip, req.GetValue()); err != nil {
+			return nil, grpcError(err)
+		}
+		return &ipampb.AllocateResponse{Entry: grpcEntry(ip, req.GetValue())}, nil
+	}
+	ip, err := s.tree(ctx).AllocateIP(req.GetFrom(), req.GetValue())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &ipampb.AllocateResponse{Entry: grpcEntry(ip, req.GetValue())}, nil
+}
+
+func (s *GrpcServer) Release(ctx context.Context, req *ipampb.ReleaseRequest) (*ipampb.ReleaseResponse, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if err := s.tree(ctx).ReleaseIP(req.GetAddress()); err != nil {
+		return nil, grpcError(err)
+	}
+	return &ipampb.ReleaseResponse{}, nil
+}
+
+func (s *GrpcServer) Lookup(ctx context.Context, req *ipampb.LookupRequest) (*ipampb.Entry, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	k, _, err := Canonicalize(req.GetKey())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	var value string
+	var ok bool
+	switch {
+	case strings.Contains(k, "-"):
+		value, ok = s.ipam.GetRange(k)
+	case strings.Contains(k, "/"):
+		value, ok = s.ipam.GetPrefix(k)
+	default:
+		value, ok = s.ipam.GetIP(k)
+	}
+	if !ok {
+		return nil, grpcError(errors.Wrapf(ErrNotFound, "%s", k))
+	}
+	return grpcEntry(k, value), nil
+}
+
+func (s *GrpcServer) LongestMatch(ctx context.Context, req *ipampb.LongestMatchRequest) (*ipampb.LongestMatchResponse, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	r, err := s.ipam.Lookup(req.GetAddress())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	resp := &ipampb.LongestMatchResponse{}
+	for _, e := range r.Chain {
+		if strings.Contains(e.Key, "/") {
+			resp.Prefix = e.Key
+		}
+		resp.Entries = append(resp.Entries, grpcEntry(e.Key, e.Value))
+	}
+	return resp, nil
+}
+
+func (s *GrpcServer) ListEntries(ctx context.Context, req *ipampb.ListEntriesRequest) (*ipampb.ListEntriesResponse, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	var entries []Entry[string]
+	switch req.GetFamily() {
+	case ipampb.Family_FAMILY_IPV4:
+		entries = s.ipam.ExportFamily(FamilyIPv4)
+	case ipampb.Family_FAMILY_IPV6:
+		entries = s.ipam.ExportFamily(FamilyIPv6)
+	default:
+		entries = s.ipam.Export()
+	}
+	resp := &ipampb.ListEntriesResponse{}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, grpcEntry(e.Key, e.Value))
+	}
+	return resp, nil
+}
+
+func grpcEntry(k, value string) *ipampb.Entry {
+	e := &ipampb.Entry{Key: k, Value: value}
+	switch {
+	case strings.Contains(k, "-"):
+		e.Kind = ipampb.EntryKind_ENTRY_KIND_RANGE
+	case strings.Contains(k, "/"):
+		e.Kind = ipampb.EntryKind_ENTRY_KIND_PREFIX
+	default:
+		e.Kind = ipampb.EntryKind_ENTRY_KIND_ADDRESS
+	}
+	return e
+}
+
+// grpcError maps the errors of the ipam tree to grpc status codes
+func grpcError(err error) error {
+	if err == nil {
+		return status.Error(codes.Internal, "validation failed")
+	}
+	switch cause := errors.Cause(err); {
+	case cause == ErrOverlap:
+		return status.Error(codes.AlreadyExists, err.Error())
+	case cause == ErrRevisionConflict:
+		return status.Error(codes.Aborted, err.Error())
+	case IsConflict(err):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case cause == ErrNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case cause == ErrExhausted:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case cause == ErrInvalid, cause == ErrMixedFamily:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
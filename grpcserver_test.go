@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/henderiw/lpm/ipampb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newInProcessClient serves the ipam service of s on an in-memory listener
+// and returns a client connected to it, the server is stopped when the
+// test ends
+func newInProcessClient(t *testing.T, s *GrpcServer) ipampb.IpamClient {
+	t.Helper()
+	l := bufconn.Listen(1024 * 1024)
+	g := grpc.NewServer()
+	s.Register(g)
+	go g.Serve(l)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		g.Stop()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		g.Stop()
+	})
+	return ipampb.NewIpamClient(conn)
+}
+
+// grpcTree returns a client of a tree with a prefix, a range in it and an
+// allocated ip address
+func grpcTree(t *testing.T) (ipampb.IpamClient, *IpTree[string]) {
+	t.Helper()
+	ipam := New[string]()
+	c := newInProcessClient(t, NewGrpcServer(ipam))
+	ctx := context.Background()
+	if _, err := c.AddPrefix(ctx, &ipampb.AddPrefixRequest{Prefix: "10.0.0.0/24", Value: "net"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.AddRange(ctx, &ipampb.AddRangeRequest{Range: "10.0.0.10-10.0.0.20", Value: "pool"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Allocate(ctx, &ipampb.AllocateRequest{Address: "10.0.0.5", Value: "host"}); err != nil {
+		t.Fatal(err)
+	}
+	return c, ipam
+}
+
+func TestGrpcGet(t *testing.T) {
+	c, _ := grpcTree(t)
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		get  func() (*ipampb.Entry, error)
+		code codes.Code
+		want *ipampb.Entry
+	}{
+		{"prefix", func() (*ipampb.Entry, error) {
+			return c.GetPrefix(ctx, &ipampb.GetPrefixRequest{Prefix: "10.0.0.0/24"})
+		}, codes.OK, &ipampb.Entry{Key: "10.0.0.0/24", Value: "net", Kind: ipampb.EntryKind_ENTRY_KIND_PREFIX}},
+		{"range", func() (*ipampb.Entry, error) {
+			return c.GetRange(ctx, &ipampb.GetRangeRequest{Range: "10.0.0.10-10.0.0.20"})
+		}, codes.OK, &ipampb.Entry{Key: "10.0.0.10-10.0.0.20", Value: "pool", Kind: ipampb.EntryKind_ENTRY_KIND_RANGE}},
+		{"address", func() (*ipampb.Entry, error) {
+			return c.Lookup(ctx, &ipampb.LookupRequest{Key: "10.0.0.5"})
+		}, codes.OK, &ipampb.Entry{Key: "10.0.0.5", Value: "host", Kind: ipampb.EntryKind_ENTRY_KIND_ADDRESS}},
+		{"prefix of an address", func() (*ipampb.Entry, error) {
+			return c.GetPrefix(ctx, &ipampb.GetPrefixRequest{Prefix: "10.0.0.5"})
+		}, codes.InvalidArgument, nil},
+		{"prefix of a range", func() (*ipampb.Entry, error) {
+			return c.GetPrefix(ctx, &ipampb.GetPrefixRequest{Prefix: "10.0.0.10-10.0.0.20"})
+		}, codes.InvalidArgument, nil},
+		{"range of a prefix", func() (*ipampb.Entry, error) {
+			return c.GetRange(ctx, &ipampb.GetRangeRequest{Range: "10.0.0.0/24"})
+		}, codes.InvalidArgument, nil},
+		{"missing prefix", func() (*ipampb.Entry, error) {
+			return c.GetPrefix(ctx, &ipampb.GetPrefixRequest{Prefix: "10.1.0.0/24"})
+		}, codes.NotFound, nil},
+		{"invalid key", func() (*ipampb.Entry, error) {
+			return c.Lookup(ctx, &ipampb.LookupRequest{Key: "not-an-address"})
+		}, codes.InvalidArgument, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := tt.get()
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("code %s (%v), want %s", code, err, tt.code)
+			}
+			if tt.want == nil {
+				return
+			}
+			if e.GetKey() != tt.want.Key || e.GetValue() != tt.want.Value || e.GetKind() != tt.want.Kind {
+				t.Errorf("entry %v, want %v", e, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrpcErrorCodes(t *testing.T) {
+	c, _ := grpcTree(t)
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{"overlapping range", func() error {
+			_, err := c.AddRange(ctx, &ipampb.AddRangeRequest{Range: "10.0.0.15-10.0.0.30"})
+			return err
+		}, codes.AlreadyExists},
+		{"range without parent", func() error {
+			_, err := c.AddRange(ctx, &ipampb.AddRangeRequest{Range: "10.1.0.1-10.1.0.2"})
+			return err
+		}, codes.FailedPrecondition},
+		{"add a prefix as range", func() error {
+			_, err := c.AddRange(ctx, &ipampb.AddRangeRequest{Range: "10.0.1.0/24"})
+			return err
+		}, codes.InvalidArgument},
+		{"add an address as prefix", func() error {
+			_, err := c.AddPrefix(ctx, &ipampb.AddPrefixRequest{Prefix: "10.0.0.7"})
+			return err
+		}, codes.InvalidArgument},
+		{"add a range as prefix", func() error {
+			_, err := c.AddPrefix(ctx, &ipampb.AddPrefixRequest{Prefix: "10.0.0.30-10.0.0.40"})
+			return err
+		}, codes.InvalidArgument},
+		{"allocated address", func() error {
+			_, err := c.Allocate(ctx, &ipampb.AllocateRequest{Address: "10.0.0.5"})
+			return err
+		}, codes.AlreadyExists},
+		{"address without parent", func() error {
+			_, err := c.Allocate(ctx, &ipampb.AllocateRequest{Address: "192.168.0.1"})
+			return err
+		}, codes.FailedPrecondition},
+		{"release of a free address", func() error {
+			_, err := c.Release(ctx, &ipampb.ReleaseRequest{Address: "10.0.0.6"})
+			return err
+		}, codes.NotFound},
+		{"strict delete with children", func() error {
+			_, err := c.DeletePrefix(ctx, &ipampb.DeletePrefixRequest{Prefix: "10.0.0.0/24", Mode: ipampb.DeleteMode_DELETE_MODE_STRICT})
+			return err
+		}, codes.FailedPrecondition},
+		{"delete of a missing range", func() error {
+			_, err := c.DeleteRange(ctx, &ipampb.DeleteRangeRequest{Range: "10.0.0.30-10.0.0.40"})
+			return err
+		}, codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := status.Code(tt.call()); code != tt.code {
+				t.Errorf("code %s, want %s", code, tt.code)
+			}
+		})
+	}
+}
+
+func TestGrpcAllocateAndRelease(t *testing.T) {
+	c, ipam := grpcTree(t)
+	ctx := context.Background()
+	resp, err := c.Allocate(ctx, &ipampb.AllocateRequest{From: "10.0.0.10-10.0.0.20", Value: "dyn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ip := resp.GetEntry().GetKey()
+	if ip != "10.0.0.10" {
+		t.Errorf("allocated %s, want 10.0.0.10", ip)
+	}
+	if v, ok := ipam.GetIP(ip); !ok || v != "dyn" {
+		t.Errorf("tree holds %q %t for %s", v, ok, ip)
+	}
+	if _, err := c.Release(ctx, &ipampb.ReleaseRequest{Address: ip}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ipam.GetIP(ip); ok {
+		t.Errorf("%s is still allocated", ip)
+	}
+}
+
+func TestGrpcDeletePrefix(t *testing.T) {
+	c, ipam := grpcTree(t)
+	ctx := context.Background()
+	resp, err := c.DeletePrefix(ctx, &ipampb.DeletePrefixRequest{Prefix: "10.0.0.0/24", Mode: ipampb.DeleteMode_DELETE_MODE_CASCADE, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.GetDeleted()) != 3 || len(ipam.Export()) != 3 {
+		t.Fatalf("dry run deleted %v, tree holds %d entries", resp.GetDeleted(), len(ipam.Export()))
+	}
+	if _, err := c.DeletePrefix(ctx, &ipampb.DeletePrefixRequest{Prefix: "10.0.0.0/24", Mode: ipampb.DeleteMode_DELETE_MODE_CASCADE}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(ipam.Export()); n != 0 {
+		t.Errorf("tree holds %d entries after a cascade", n)
+	}
+}
+
+func TestGrpcDeletePrefixDefaultMode(t *testing.T) {
+	c, ipam := grpcTree(t)
+	ctx := context.Background()
+	if err := ipam.AddPrefix("10.0.0.0/16", "parent"); err != nil {
+		t.Fatal(err)
+	}
+	// an unspecified mode reparents like the library DeletePrefix
+	resp, err := c.DeletePrefix(ctx, &ipampb.DeletePrefixRequest{Prefix: "10.0.0.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"10.0.0.0/24"}; !reflect.DeepEqual(resp.GetDeleted(), want) {
+		t.Errorf("deleted %v, want %v", resp.GetDeleted(), want)
+	}
+	if got, want := exportKeys(ipam), []string{"10.0.0.0/16", "10.0.0.10-10.0.0.20", "10.0.0.5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tree holds %v, want %v", got, want)
+	}
+}
+
+func TestGrpcListEntries(t *testing.T) {
+	c, _ := grpcTree(t)
+	ctx := context.Background()
+	if _, err := c.AddPrefix(ctx, &ipampb.AddPrefixRequest{Prefix: "2001:db8::/32", Value: "v6"}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		family ipampb.Family
+		want   []string
+	}{
+		{ipampb.Family_FAMILY_UNSPECIFIED, []string{"10.0.0.0/24", "10.0.0.10-10.0.0.20", "10.0.0.5", "2001:db8::/32"}},
+		{ipampb.Family_FAMILY_IPV4, []string{"10.0.0.0/24", "10.0.0.10-10.0.0.20", "10.0.0.5"}},
+		{ipampb.Family_FAMILY_IPV6, []string{"2001:db8::/32"}},
+	}
+	for _, tt := range tests {
+		resp, err := c.ListEntries(ctx, &ipampb.ListEntriesRequest{Family: tt.family})
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys := []string{}
+		for _, e := range resp.GetEntries() {
+			keys = append(keys, e.GetKey())
+		}
+		sort.Strings(keys)
+		if len(keys) != len(tt.want) {
+			t.Errorf("%s: entries %v, want %v", tt.family, keys, tt.want)
+			continue
+		}
+		for i := range keys {
+			if keys[i] != tt.want[i] {
+				t.Errorf("%s: entries %v, want %v", tt.family, keys, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestGrpcLongestMatch(t *testing.T) {
+	c, _ := grpcTree(t)
+	resp, err := c.LongestMatch(context.Background(), &ipampb.LongestMatchRequest{Address: "10.0.0.15"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{}
+	for _, e := range resp.GetEntries() {
+		keys = append(keys, e.GetKey())
+	}
+	if resp.GetPrefix() != "10.0.0.0/24" || len(keys) != 2 || keys[0] != "10.0.0.0/24" || keys[1] != "10.0.0.10-10.0.0.20" {
+		t.Errorf("longest match %s %v, want the prefix and the range", resp.GetPrefix(), keys)
+	}
+}
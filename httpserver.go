@@ -0,0 +1,420 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// HttpEntry is a prefix, range or ip address returned by the http api
+type HttpEntry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// Kind is prefix, range or address
+	Kind string `json:"kind"`
+}
+
+type HttpAddPrefixRequest struct {
+	Prefix string `json:"prefix"`
+	Value  string `json:"value"`
+}
+
+type HttpAddRangeRequest struct {
+	Range string `json:"range"`
+	Value string `json:"value"`
+}
+
+// HttpAllocateRequest allocates the first free ip address of the prefix or
+// range in From, or the specific ip address in Address
+type HttpAllocateRequest struct {
+	From    string `json:"from,omitempty"`
+	Address string `json:"address,omitempty"`
+	Value   string `json:"value"`
+}
+
+type HttpDeletePrefixResponse struct {
+	Deleted    []string `json:"deleted"`
+	Reparented []string `json:"reparented"`
+	Parent     string   `json:"parent,omitempty"`
+}
+
+// HttpLookupResponse holds the most specific prefix that holds the ip
+// address and the prefixes, ranges and allocated address that hold it
+// ordered from the least to the most specific
+type HttpLookupResponse struct {
+	Prefix  string      `json:"prefix"`
+	Entries []HttpEntry `json:"entries"`
+}
+
+// HttpError is the body returned for a failed request, Code is the reason
+// of the failure, e.g. overlap or inside-range for a rejection by the policy
+type HttpError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type HttpErrorResponse struct {
+	Error HttpError `json:"error"`
+}
+
+// HttpServer exposes an ipam tree as rest resources, the requests are
+// serialized since the tree is not safe for concurrent use
+type HttpServer struct {
+	m    sync.Mutex
+	ipam *IpTree[string]
+	// pools are the prefixes and ranges of which the utilization is
+	// exposed on /metrics
+	pools []string
+}
+
+// NewHttpServer returns a server of the tree that exposes the utilization
+// of the prefixes and ranges in pools on /metrics
+func NewHttpServer(ipam *IpTree[string], pools ...string) *HttpServer {
+	return &HttpServer{
+		ipam:  ipam,
+		pools: pools,
+	}
+}
+
+// httpRoute describes a handler, the description is used to generate the
+// openapi document
+type httpRoute struct {
+	method  string
+	path    string
+	summary string
+	query   []string
+	// request and response are values of the types of the bodies
+	request  interface{}
+	response interface{}
+	status   int
+	handler  http.HandlerFunc
+}
+
+func (s *HttpServer) routes() []httpRoute {
+	return []httpRoute{
+		{method: "GET", path: "/prefixes", summary: "list the prefixes", response: []HttpEntry{}, status: http.StatusOK, handler: s.listEntries("prefix")},
+		{method: "POST", path: "/prefixes", summary: "add a prefix", request: HttpAddPrefixRequest{}, response: HttpEntry{}, status: http.StatusCreated, handler: s.addPrefix},
+		{method: "GET", path: "/prefixes/{prefix...}", summary: "get a prefix", response: HttpEntry{}, status: http.StatusOK, handler: s.getEntry("prefix")},
+		{method: "DELETE", path: "/prefixes/{prefix...}", summary: "delete a prefix, mode is reparent (default), strict or cascade", query: []string{"mode", "dryRun"}, response: HttpDeletePrefixResponse{}, status: http.StatusOK, handler: s.deletePrefix},
+		{method: "GET", path: "/ranges", summary: "list the ranges", response: []HttpEntry{}, status: http.StatusOK, handler: s.listEntries("range")},
+		{method: "POST", path: "/ranges", summary: "add a range", request: HttpAddRangeRequest{}, response: HttpEntry{}, status: http.StatusCreated, handler: s.addRange},
+		{method: "GET", path: "/ranges/{range}", summary: "get a range", response: HttpEntry{}, status: http.StatusOK, handler: s.getEntry("range")},
+		{method: "DELETE", path: "/ranges/{range}", summary: "delete a range", status: http.StatusNoContent, handler: s.deleteRange},
+		{method: "GET", path: "/allocations", summary: "list the allocated ip addresses, optionally of the prefix or range in from", query: []string{"from"}, response: []HttpEntry{}, status: http.StatusOK, handler: s.listAllocations},
+		{method: "POST", path: "/allocations", summary: "allocate an ip address", request: HttpAllocateRequest{}, response: HttpEntry{}, status: http.StatusCreated, handler: s.allocate},
+		{method: "GET", path: "/allocations/{address}", summary: "get an allocated ip address", response: HttpEntry{}, status: http.StatusOK, handler: s.getEntry("address")},
+		{method: "DELETE", path: "/allocations/{address}", summary: "release an allocated ip address", status: http.StatusNoContent, handler: s.release},
+		{method: "GET", path: "/lookup/{ip}", summary: "longest prefix match of an ip address with the prefixes and ranges that hold it", response: HttpLookupResponse{}, status: http.StatusOK, handler: s.lookup},
+	}
+}
+
+// Handler returns the http handler serving the api, its openapi document
+// on /openapi.json and the prometheus metrics of the tree on /metrics
+func (s *HttpServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, rt := range s.routes() {
+		mux.HandleFunc(rt.method+" "+rt.path, rt.handler)
+	}
+	mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.OpenAPI())
+	})
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(s.ipam.Collector(&s.m, s.pools...))
+	mux.Handle("GET /metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	return mux
+}
+
+// Locker returns the lock that serializes the requests, another server of
+// the tree such as the dhcp server takes it to share the tree
+func (s *HttpServer) Locker() sync.Locker {
+	return &s.m
+}
+
+// tree returns the view of the tree for the actor and reason of the
+// X-Actor and X-Reason headers of the request, they are recorded in the
+// audit log
+func (s *HttpServer) tree(r *http.Request) *IpTree[string] {
+	return s.ipam.As(r.Header.Get("X-Actor"), r.Header.Get("X-Reason"))
+}
+
+// Serve serves the http api on the listener until it fails
+func (s *HttpServer) Serve(l net.Listener) error {
+	return http.Serve(l, s.Handler())
+}
+
+func (s *HttpServer) listEntries(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.m.Lock()
+		defer s.m.Unlock()
+		entries := []HttpEntry{}
+		for _, e := range s.ipam.Export() {
+			if he := httpEntry(e.Key, e.Value); he.Kind == kind {
+				entries = append(entries, he)
+			}
+		}
+		writeJSON(w, http.StatusOK, entries)
+	}
+}
+
+func (s *HttpServer) getEntry(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.m.Lock()
+		defer s.m.Unlock()
+		k, _, err := Canonicalize(r.PathValue(kind))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		var value string
+		var ok bool
+		switch kind {
+		case "range":
+			value, ok = s.ipam.GetRange(k)
+		case "prefix":
+			value, ok = s.ipam.GetPrefix(k)
+		default:
+			value, ok = s.ipam.GetIP(k)
+		}
+		if !ok {
+			writeError(w, errors.Wrapf(ErrNotFound, "%s %s", kind, k))
+			return
+		}
+		writeJSON(w, http.StatusOK, httpEntry(k, value))
+	}
+}
+
+func (s *HttpServer) addPrefix(w http.ResponseWriter, r *http.Request) {
+	req := HttpAddPrefixRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, errors.Wrapf(ErrInvalid, "cannot decode request: %v", err))
+		return
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	p, _, err := Canonicalize(req.Prefix)
+	if err == nil && !strings.Contains(p, "/") {
+		err = errors.Wrapf(ErrInvalid, "%s is not a prefix", p)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if ok, err := s.ipam.PreCheckAddition(p); !ok {
+		writeError(w, err)
+		return
+	}
+	if err := s.tree(r).AddPrefix(p, req.Value); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, httpEntry(p, req.Value))
+}
+
+func (s *HttpServer) deletePrefix(w http.ResponseWriter, r *http.Request) {
+	o := DeleteOptions{}
+	switch mode := r.URL.Query().Get("mode"); mode {
+	case "", "reparent":
+		o.Mode = DeleteReparent
+	case "strict":
+		o.Mode = DeleteStrict
+	case "cascade":
+		o.Mode = DeleteCascade
+	default:
+		writeError(w, errors.Wrapf(ErrInvalid, "unknown delete mode %s", mode))
+		return
+	}
+	if dryRun := r.URL.Query().Get("dryRun"); dryRun != "" {
+		var err error
+		if o.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+			writeError(w, errors.Wrapf(ErrInvalid, "invalid dryRun %s", dryRun))
+			return
+		}
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	result, err := s.tree(r).DeletePrefixWithOptions(r.PathValue("prefix"), o)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, HttpDeletePrefixResponse{
+		Deleted:    result.Deleted,
+		Reparented: result.Reparented,
+		Parent:     result.Parent,
+	})
+}
+
+func (s *HttpServer) addRange(w http.ResponseWriter, r *http.Request) {
+	req := HttpAddRangeRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, errors.Wrapf(ErrInvalid, "cannot decode request: %v", err))
+		return
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	ra, _, err := Canonicalize(req.Range)
+	if err == nil && !strings.Contains(ra, "-") {
+		err = errors.Wrapf(ErrInvalid, "%s is not a range", ra)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if ok, err := s.ipam.PreCheckAddition(ra); !ok {
+		writeError(w, err)
+		return
+	}
+	if err := s.tree(r).AddRange(ra, req.Value); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, httpEntry(ra, req.Value))
+}
+
+func (s *HttpServer) deleteRange(w http.ResponseWriter, r *http.Request) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	ra, _, err := Canonicalize(r.PathValue("range"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if _, ok := s.ipam.GetRange(ra); !ok {
+		writeError(w, errors.Wrapf(ErrNotFound, "range %s", ra))
+		return
+	}
+	if err := s.tree(r).DeleteRange(ra); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *HttpServer) listAllocations(w http.ResponseWriter, r *http.Request) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	entries := []HttpEntry{}
+	if from := r.URL.Query().Get("from"); from != "" {
+		allocations, err := s.ipam.Allocations(from)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		for _, e := range allocations {
+			entries = append(entries, httpEntry(e.Key, e.Value))
+		}
+	} else {
+		for _, e := range s.ipam.Export() {
+			if he := httpEntry(e.Key, e.Value); he.Kind == "address" {
+				entries = append(entries, he)
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (s *HttpServer) allocate(w http.ResponseWriter, r *http.Request) {
+	req := HttpAllocateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, errors.Wrapf(ErrInvalid, "cannot decode request: %v", err))
+		return
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	if req.Address != "" {
+		ip, _, err := Canonicalize(req.Address)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := s.tree(r).ClaimIP(ip, req.Value); err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, httpEntry(ip, req.Value))
+		return
+	}
+	ip, err := s.tree(r).AllocateIP(req.From, req.Value)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, httpEntry(ip, req.Value))
+}
+
+func (s *HttpServer) release(w http.ResponseWriter, r *http.Request) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if err := s.tree(r).ReleaseIP(r.PathValue("address")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *HttpServer) lookup(w http.ResponseWriter, r *http.Request) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	result, err := s.ipam.Lookup(r.PathValue("ip"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp := HttpLookupResponse{Entries: []HttpEntry{}}
+	for _, e := range result.Chain {
+		he := httpEntry(e.Key, e.Value)
+		if he.Kind == "prefix" {
+			resp.Prefix = e.Key
+		}
+		resp.Entries = append(resp.Entries, he)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func httpEntry(k, value string) HttpEntry {
+	e := HttpEntry{Key: k, Value: value}
+	switch {
+	case strings.Contains(k, "-"):
+		e.Kind = "range"
+	case strings.Contains(k, "/"):
+		e.Kind = "prefix"
+	default:
+		e.Kind = "address"
+	}
+	return e
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes the typed error body with the http status of the error
+func writeError(w http.ResponseWriter, err error) {
+	if err == nil {
+		err = errors.New("validation failed")
+	}
+	status := http.StatusInternalServerError
+	switch cause := errors.Cause(err); {
+	case IsConflict(err), cause == ErrExhausted:
+		status = http.StatusConflict
+	case cause == ErrNotFound:
+		status = http.StatusNotFound
+	case cause == ErrInvalid, cause == ErrMixedFamily:
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, HttpErrorResponse{
+		Error: HttpError{
+			Code:    ErrorReason(err),
+			Message: err.Error(),
+		},
+	})
+}
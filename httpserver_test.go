@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// httpTree serves a tree with a prefix, a range in it and an allocated ip
+// address, the server is closed when the test ends
+func httpTree(t *testing.T) (*httptest.Server, *IpTree[string]) {
+	t.Helper()
+	ipam := New[string]()
+	srv := httptest.NewServer(NewHttpServer(ipam).Handler())
+	t.Cleanup(srv.Close)
+	for _, rq := range []struct{ path, body string }{
+		{"/prefixes", `{"prefix": "10.0.0.0/24", "value": "net"}`},
+		{"/ranges", `{"range": "10.0.0.10-10.0.0.20", "value": "pool"}`},
+		{"/allocations", `{"address": "10.0.0.5", "value": "host"}`},
+	} {
+		if status, body := httpDo(t, srv, "POST", rq.path, rq.body); status != http.StatusCreated {
+			t.Fatalf("POST %s returned %d: %s", rq.path, status, body)
+		}
+	}
+	return srv, ipam
+}
+
+// httpDo sends a request with the json body to the server and returns the
+// status and the body of the response
+func httpDo(t *testing.T, srv *httptest.Server, method, path, body string) (int, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	buf := new(bytes.Buffer)
+	if _, err := buf.ReadFrom(resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, buf.Bytes()
+}
+
+func TestHttpGet(t *testing.T) {
+	srv, _ := httpTree(t)
+	tests := []struct {
+		path string
+		want HttpEntry
+	}{
+		{"/prefixes/10.0.0.0/24", HttpEntry{Key: "10.0.0.0/24", Value: "net", Kind: "prefix"}},
+		{"/ranges/10.0.0.10-10.0.0.20", HttpEntry{Key: "10.0.0.10-10.0.0.20", Value: "pool", Kind: "range"}},
+		{"/allocations/10.0.0.5", HttpEntry{Key: "10.0.0.5", Value: "host", Kind: "address"}},
+	}
+	for _, tt := range tests {
+		status, body := httpDo(t, srv, "GET", tt.path, "")
+		if status != http.StatusOK {
+			t.Errorf("GET %s returned %d: %s", tt.path, status, body)
+			continue
+		}
+		got := HttpEntry{}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("GET %s = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestHttpList(t *testing.T) {
+	srv, _ := httpTree(t)
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/prefixes", []string{"10.0.0.0/24"}},
+		{"/ranges", []string{"10.0.0.10-10.0.0.20"}},
+		{"/allocations", []string{"10.0.0.5"}},
+		{"/allocations?from=10.0.0.10-10.0.0.20", []string{}},
+	}
+	for _, tt := range tests {
+		status, body := httpDo(t, srv, "GET", tt.path, "")
+		if status != http.StatusOK {
+			t.Errorf("GET %s returned %d: %s", tt.path, status, body)
+			continue
+		}
+		entries := []HttpEntry{}
+		if err := json.Unmarshal(body, &entries); err != nil {
+			t.Fatal(err)
+		}
+		keys := []string{}
+		for _, e := range entries {
+			keys = append(keys, e.Key)
+		}
+		sort.Strings(keys)
+		if !reflect.DeepEqual(keys, tt.want) {
+			t.Errorf("GET %s = %v, want %v", tt.path, keys, tt.want)
+		}
+	}
+}
+
+func TestHttpAllocateAndRelease(t *testing.T) {
+	srv, ipam := httpTree(t)
+	status, body := httpDo(t, srv, "POST", "/allocations", `{"from": "10.0.0.10-10.0.0.20", "value": "dyn"}`)
+	if status != http.StatusCreated {
+		t.Fatalf("allocate returned %d: %s", status, body)
+	}
+	e := HttpEntry{}
+	if err := json.Unmarshal(body, &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Key != "10.0.0.10" || e.Kind != "address" {
+		t.Errorf("allocated %+v, want 10.0.0.10", e)
+	}
+	if status, body := httpDo(t, srv, "DELETE", "/allocations/"+e.Key, ""); status != http.StatusNoContent {
+		t.Fatalf("release returned %d: %s", status, body)
+	}
+	if _, ok := ipam.GetIP(e.Key); ok {
+		t.Errorf("%s is still allocated", e.Key)
+	}
+}
+
+func TestHttpDelete(t *testing.T) {
+	srv, ipam := httpTree(t)
+	if status, body := httpDo(t, srv, "DELETE", "/ranges/10.0.0.10-10.0.0.20", ""); status != http.StatusNoContent {
+		t.Fatalf("delete range returned %d: %s", status, body)
+	}
+	status, body := httpDo(t, srv, "DELETE", "/prefixes/10.0.0.0/24?mode=cascade&dryRun=true", "")
+	if status != http.StatusOK {
+		t.Fatalf("dry run returned %d: %s", status, body)
+	}
+	resp := HttpDeletePrefixResponse{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"10.0.0.5", "10.0.0.0/24"}; !reflect.DeepEqual(resp.Deleted, want) {
+		t.Errorf("dry run deleted %v, want %v", resp.Deleted, want)
+	}
+	if n := len(ipam.Export()); n != 2 {
+		t.Errorf("dry run left %d entries, want 2", n)
+	}
+	if status, body := httpDo(t, srv, "DELETE", "/prefixes/10.0.0.0/24?mode=cascade", ""); status != http.StatusOK {
+		t.Fatalf("cascade returned %d: %s", status, body)
+	}
+	if n := len(ipam.Export()); n != 0 {
+		t.Errorf("tree holds %d entries after a cascade", n)
+	}
+}
+
+func TestHttpDeleteDefaultMode(t *testing.T) {
+	srv, ipam := httpTree(t)
+	if err := ipam.AddPrefix("10.0.0.0/16", "parent"); err != nil {
+		t.Fatal(err)
+	}
+	// a delete without a mode reparents like the library DeletePrefix
+	if status, body := httpDo(t, srv, "DELETE", "/prefixes/10.0.0.0/24", ""); status != http.StatusOK {
+		t.Fatalf("delete returned %d: %s", status, body)
+	}
+	if got, want := exportKeys(ipam), []string{"10.0.0.0/16", "10.0.0.10-10.0.0.20", "10.0.0.5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tree holds %v, want %v", got, want)
+	}
+}
+
+func TestHttpErrors(t *testing.T) {
+	srv, _ := httpTree(t)
+	tests := []struct {
+		name         string
+		method, path string
+		body         string
+		status       int
+		code         string
+	}{
+		{"overlapping range", "POST", "/ranges", `{"range": "10.0.0.15-10.0.0.30"}`, http.StatusConflict, "overlap"},
+		{"range without parent", "POST", "/ranges", `{"range": "10.1.0.1-10.1.0.2"}`, http.StatusConflict, "no-parent"},
+		{"prefix as range", "POST", "/ranges", `{"range": "10.0.1.0/24"}`, http.StatusBadRequest, "parse-error"},
+		{"range as prefix", "POST", "/prefixes", `{"prefix": "10.0.1.1-10.0.1.2"}`, http.StatusBadRequest, "parse-error"},
+		{"invalid body", "POST", "/prefixes", `{`, http.StatusBadRequest, "parse-error"},
+		{"allocated address", "POST", "/allocations", `{"address": "10.0.0.5"}`, http.StatusConflict, "overlap"},
+		{"missing prefix", "GET", "/prefixes/10.1.0.0/24", "", http.StatusNotFound, "not-found"},
+		{"missing range", "DELETE", "/ranges/10.0.0.30-10.0.0.40", "", http.StatusNotFound, "not-found"},
+		{"free address", "DELETE", "/allocations/10.0.0.6", "", http.StatusNotFound, "not-found"},
+		{"strict delete with children", "DELETE", "/prefixes/10.0.0.0/24?mode=strict", "", http.StatusConflict, "has-children"},
+		{"unknown delete mode", "DELETE", "/prefixes/10.0.0.0/24?mode=all", "", http.StatusBadRequest, "parse-error"},
+		{"invalid address", "GET", "/lookup/10.0.0", "", http.StatusBadRequest, "parse-error"},
+		{"no match", "GET", "/lookup/192.168.0.1", "", http.StatusNotFound, "not-found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := httpDo(t, srv, tt.method, tt.path, tt.body)
+			if status != tt.status {
+				t.Errorf("%s %s returned %d, want %d: %s", tt.method, tt.path, status, tt.status, body)
+			}
+			resp := HttpErrorResponse{}
+			if err := json.Unmarshal(body, &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Error.Code != tt.code {
+				t.Errorf("%s %s returned code %s, want %s", tt.method, tt.path, resp.Error.Code, tt.code)
+			}
+		})
+	}
+}
+
+func TestHttpOpenAPI(t *testing.T) {
+	srv, _ := httpTree(t)
+	status, body := httpDo(t, srv, "GET", "/openapi.json", "")
+	if status != http.StatusOK {
+		t.Fatalf("GET /openapi.json returned %d", status)
+	}
+	doc := struct {
+		OpenAPI string                                       `json:"openapi"`
+		Paths   map[string]map[string]map[string]interface{} `json:"paths"`
+	}{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != "3.0.3" {
+		t.Errorf("openapi version %s", doc.OpenAPI)
+	}
+	// every route is documented, catch-all path segments are plain
+	// parameters in the document
+	for _, rt := range NewHttpServer(New[string]()).routes() {
+		path := strings.ReplaceAll(rt.path, "...}", "}")
+		op, ok := doc.Paths[path][strings.ToLower(rt.method)]
+		if !ok {
+			t.Errorf("%s %s is not documented", rt.method, path)
+			continue
+		}
+		if op["operationId"] == "" {
+			t.Errorf("%s %s has no operation id", rt.method, path)
+		}
+		if _, ok := op["requestBody"]; ok != (rt.request != nil) {
+			t.Errorf("%s %s documents a request body %t", rt.method, path, ok)
+		}
+	}
+}
+
+func TestHttpLookup(t *testing.T) {
+	srv, _ := httpTree(t)
+	tests := []struct {
+		ip     string
+		prefix string
+		keys   []string
+	}{
+		{"10.0.0.5", "10.0.0.0/24", []string{"10.0.0.0/24", "10.0.0.5"}},
+		{"10.0.0.15", "10.0.0.0/24", []string{"10.0.0.0/24", "10.0.0.10-10.0.0.20"}},
+		{"10.0.0.30", "10.0.0.0/24", []string{"10.0.0.0/24"}},
+	}
+	for _, tt := range tests {
+		status, body := httpDo(t, srv, "GET", "/lookup/"+tt.ip, "")
+		if status != http.StatusOK {
+			t.Errorf("GET /lookup/%s returned %d: %s", tt.ip, status, body)
+			continue
+		}
+		resp := HttpLookupResponse{}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			t.Fatal(err)
+		}
+		keys := []string{}
+		for _, e := range resp.Entries {
+			keys = append(keys, e.Key)
+		}
+		if resp.Prefix != tt.prefix || !reflect.DeepEqual(keys, tt.keys) {
+			t.Errorf("lookup %s = %s %v, want %s %v", tt.ip, resp.Prefix, keys, tt.prefix, tt.keys)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ImportRecord is a prefix, range or ip address read from an export of
+// another ipam or from a routing table
+type ImportRecord struct {
+	// Source locates the record in the input, e.g. line 3
+	Source      string
+	Key         string
+	Description string
+	Tags        []string
+	// Err is set when the record cannot be parsed, it is rejected
+	Err error
+}
+
+// ImportRejection is a record that was not imported and why
+type ImportRejection struct {
+	Source string
+	Key    string
+	// Reason is the ErrorReason of the error, e.g. overlap or parse-error
+	Reason string
+	Err    error
+}
+
+// ImportReport is the outcome of an import
+type ImportReport struct {
+	Imported []string
+	Rejected []ImportRejection
+}
+
+// String returns the report in a human readable form
+func (r *ImportReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d imported, %d rejected\n", len(r.Imported), len(r.Rejected))
+	for _, rj := range r.Rejected {
+		fmt.Fprintf(&sb, "%s %s: %s: %v\n", rj.Source, rj.Key, rj.Reason, rj.Err)
+	}
+	return sb.String()
+}
+
+// Import adds the records to the tree through the validated add path,
+// prefixes are added before the ranges and ip addresses they contain and
+// value returns the value stored for a record. A record that is rejected
+// does not stop the import, it is listed in the report
+func (ipam *IpTree[T]) Import(records []ImportRecord, value func(ImportRecord) T) *ImportReport {
+	report := &ImportReport{
+		Imported: []string{},
+		Rejected: []ImportRejection{},
+	}
+	reject := func(rec ImportRecord, err error) {
+		report.Rejected = append(report.Rejected, ImportRejection{
+			Source: rec.Source,
+			Key:    rec.Key,
+			Reason: ErrorReason(err),
+			Err:    err,
+		})
+	}
+
+	// a rejection by the pre-check without an error is still a rejection
+	preCheck := func(k string) error {
+		ok, err := ipam.PreCheckAddition(k)
+		if !ok && err == nil {
+			err = errors.Wrapf(ErrOverlap, "%s is rejected by the policy", k)
+		}
+		return err
+	}
+
+	valid := []ImportRecord{}
+	for _, rec := range records {
+		if rec.Err != nil {
+			reject(rec, rec.Err)
+			continue
+		}
+		k, _, err := Canonicalize(rec.Key)
+		if err != nil {
+			reject(rec, err)
+			continue
+		}
+		rec.Key = k
+		valid = append(valid, rec)
+	}
+	sort.SliceStable(valid, func(i, j int) bool {
+		return importDepth(valid[i].Key) < importDepth(valid[j].Key)
+	})
+
+	for _, rec := range valid {
+		var err error
+		switch {
+		case strings.Contains(rec.Key, "-"):
+			if _, exists := ipam.GetRange(rec.Key); exists {
+				err = errors.Wrapf(ErrOverlap, "range %s exists", rec.Key)
+				break
+			}
+			if err = preCheck(rec.Key); err != nil {
+				break
+			}
+			err = ipam.AddRange(rec.Key, value(rec))
+		case strings.Contains(rec.Key, "/"):
+			if _, exists := ipam.GetPrefix(rec.Key); exists {
+				err = errors.Wrapf(ErrOverlap, "prefix %s exists", rec.Key)
+				break
+			}
+			if err = preCheck(rec.Key); err != nil {
+				break
+			}
+			err = ipam.AddPrefix(rec.Key, value(rec))
+		default:
+			err = ipam.ClaimIP(rec.Key, value(rec))
+		}
+		if err != nil {
+			reject(rec, err)
+			continue
+		}
+		report.Imported = append(report.Imported, rec.Key)
+	}
+	return report
+}
+
+// importDepth orders prefixes by length before ranges and ip addresses
+func importDepth(k string) int {
+	if strings.Contains(k, "/") || strings.Contains(k, "-") {
+		return planDepth(k)
+	}
+	return 256
+}
+
+// netboxObject holds the fields of a NetBox prefix, ip range or ip address
+type netboxObject struct {
+	Prefix       string            `json:"prefix"`
+	StartAddress string            `json:"start_address"`
+	EndAddress   string            `json:"end_address"`
+	Address      string            `json:"address"`
+	Description  string            `json:"description"`
+	Tags         []json.RawMessage `json:"tags"`
+}
+
+// ParseNetBox reads prefixes, ip ranges and ip addresses from a NetBox json
+// export. The input is a list of objects, an api page with the objects in
+// results or an object with prefixes, ip_ranges and ip_addresses lists. The
+// mask of an ip address or range boundary is dropped
+func ParseNetBox(r io.Reader) ([]ImportRecord, error) {
+	var doc json.RawMessage
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, errors.Wrapf(ErrInvalid, "cannot decode netbox export: %v", err)
+	}
+	lists := map[string][]json.RawMessage{}
+	if objs := []json.RawMessage{}; json.Unmarshal(doc, &objs) == nil {
+		lists[""] = objs
+	} else {
+		fields := map[string]json.RawMessage{}
+		if err := json.Unmarshal(doc, &fields); err != nil {
+			return nil, errors.Wrapf(ErrInvalid, "cannot decode netbox export: %v", err)
+		}
+		// other fields of an api page like count and next are ignored
+		for name, v := range fields {
+			// every list is decoded into its own slice, a reused slice
+			// would share its backing array with the lists before it
+			objs := []json.RawMessage{}
+			if err := json.Unmarshal(v, &objs); err == nil {
+				lists[name] = objs
+			}
+		}
+	}
+
+	names := make([]string, 0, len(lists))
+	for name := range lists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	records := []ImportRecord{}
+	for _, name := range names {
+		for i, raw := range lists[name] {
+			rec := ImportRecord{Source: fmt.Sprintf("%s[%d]", name, i)}
+			o := netboxObject{}
+			if err := json.Unmarshal(raw, &o); err != nil {
+				rec.Err = errors.Wrapf(ErrInvalid, "cannot decode object: %v", err)
+				records = append(records, rec)
+				continue
+			}
+			rec.Description = o.Description
+			for _, t := range o.Tags {
+				rec.Tags = append(rec.Tags, netboxTag(t))
+			}
+			switch {
+			case o.Prefix != "":
+				rec.Key = o.Prefix
+			case o.StartAddress != "" && o.EndAddress != "":
+				rec.Key = stripMask(o.StartAddress) + "-" + stripMask(o.EndAddress)
+			case o.Address != "":
+				rec.Key = stripMask(o.Address)
+			default:
+				rec.Err = errors.Wrap(ErrInvalid, "object has no prefix, range or address")
+			}
+			records = append(records, rec)
+		}
+	}
+	return records, nil
+}
+
+// netboxTag returns the name of a tag that is either a string or an object
+// with a name
+func netboxTag(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	t := struct {
+		Name string `json:"name"`
+		Slug string `json:"slug"`
+	}{}
+	json.Unmarshal(raw, &t)
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.Slug
+}
+
+func stripMask(s string) string {
+	ip, _, _ := strings.Cut(s, "/")
+	return ip
+}
+
+// ParseCSV reads records with the columns prefix, description and tags, the
+// prefix column also accepts a range or an ip address, tags are separated
+// by commas or semicolons. A header row and lines starting with # are
+// skipped
+func ParseCSV(r io.Reader) ([]ImportRecord, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	records := []ImportRecord{}
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := cr.FieldPos(0)
+		rec := ImportRecord{Source: fmt.Sprintf("line %d", line)}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); !ok {
+				return nil, errors.Wrap(err, "cannot read csv")
+			}
+			rec.Err = errors.Wrapf(ErrInvalid, "%v", err)
+			records = append(records, rec)
+			continue
+		}
+		if len(records) == 0 && strings.EqualFold(strings.TrimSpace(row[0]), "prefix") {
+			continue
+		}
+		rec.Key = strings.TrimSpace(row[0])
+		if len(row) > 1 {
+			rec.Description = strings.TrimSpace(row[1])
+		}
+		if len(row) > 2 {
+			for _, t := range strings.FieldsFunc(row[2], func(c rune) bool { return c == ',' || c == ';' }) {
+				if t = strings.TrimSpace(t); t != "" {
+					rec.Tags = append(rec.Tags, t)
+				}
+			}
+		}
+		if len(row) > 3 {
+			rec.Err = errors.Wrapf(ErrInvalid, "%d columns, expected prefix,description,tags", len(row))
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// routeTypes are the route types of ip route, only the types that route
+// a prefix are imported
+var routeTypes = map[string]bool{
+	"unicast":     true,
+	"blackhole":   true,
+	"unreachable": true,
+	"prohibit":    true,
+	"throw":       true,
+	"local":       false,
+	"broadcast":   false,
+	"multicast":   false,
+	"anycast":     false,
+	"nat":         false,
+}
+
+// ParseIPRoute reads the prefixes of the output of ip route or ip -6 route,
+// a host route is imported as a host prefix. The description of a record is
+// the remainder of the line and the tags are the device and protocol of
+// the route. Default routes and local, broadcast and multicast routes are
+// rejected
+func ParseIPRoute(r io.Reader) ([]ImportRecord, error) {
+	records := []ImportRecord{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		rec := ImportRecord{Source: fmt.Sprintf("line %d", line)}
+		if imported, ok := routeTypes[fields[0]]; ok {
+			if !imported {
+				rec.Key = strings.Join(fields[:min(2, len(fields))], " ")
+				rec.Err = errors.Wrapf(ErrInvalid, "%s route is not imported", fields[0])
+				records = append(records, rec)
+				continue
+			}
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		rec.Key = fields[0]
+		rec.Description = strings.Join(fields[1:], " ")
+		for i := 1; i+1 < len(fields); i++ {
+			switch fields[i] {
+			case "dev":
+				rec.Tags = append(rec.Tags, "dev="+fields[i+1])
+			case "proto":
+				rec.Tags = append(rec.Tags, "proto="+fields[i+1])
+			}
+		}
+		switch {
+		case rec.Key == "default":
+			rec.Err = errors.Wrap(ErrInvalid, "default route is not imported")
+		case !strings.Contains(rec.Key, "/"):
+			if strings.Contains(rec.Key, ":") {
+				rec.Key += "/128"
+			} else {
+				rec.Key += "/32"
+			}
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "cannot read routes")
+	}
+	return records, nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestImport(t *testing.T) {
+	ipam := New[string]()
+	records := []ImportRecord{
+		{Source: "line 1", Key: "10.0.0.10-10.0.0.20"},
+		{Source: "line 2", Key: "10.0.0.0/24"},
+		{Source: "line 3", Key: "10.0.0.10-10.0.0.20"},
+		{Source: "line 4", Key: "10.0.0.0/24"},
+		{Source: "line 5", Key: "10.0.0.15-10.0.0.30"},
+		{Source: "line 6", Key: "10.0.0.12-10.0.0.14"},
+		{Source: "line 7", Key: "10.0.0.5"},
+		{Source: "line 8", Key: "10.0.0"},
+	}
+	report := ipam.Import(records, func(rec ImportRecord) string { return rec.Source })
+
+	if want := []string{"10.0.0.0/24", "10.0.0.10-10.0.0.20", "10.0.0.5"}; !reflect.DeepEqual(report.Imported, want) {
+		t.Errorf("imported %v, want %v", report.Imported, want)
+	}
+	rejected := map[string]string{}
+	for _, rj := range report.Rejected {
+		rejected[rj.Source] = rj.Reason
+	}
+	want := map[string]string{
+		"line 3": "overlap",
+		"line 4": "overlap",
+		"line 5": "overlap",
+		"line 6": "nested-range",
+		"line 8": "parse-error",
+	}
+	if !reflect.DeepEqual(rejected, want) {
+		t.Errorf("rejected %v, want %v", rejected, want)
+	}
+	if v, _ := ipam.GetRange("10.0.0.10-10.0.0.20"); v != "line 1" {
+		t.Errorf("range has value %q, want line 1", v)
+	}
+}
+
+// recordKeys returns the keys of the records and the sources of the records
+// that cannot be parsed
+func recordKeys(records []ImportRecord) ([]string, []string) {
+	keys := []string{}
+	failed := []string{}
+	for _, rec := range records {
+		if rec.Err != nil {
+			failed = append(failed, rec.Source)
+			continue
+		}
+		keys = append(keys, rec.Key)
+	}
+	return keys, failed
+}
+
+func TestParseNetBox(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		keys   []string
+		failed []string
+	}{
+		{
+			name: "lists",
+			input: `{
+				"prefixes": [{"prefix": "10.0.0.0/24", "tags": [{"name": "core"}]}, {"prefix": "10.0.1.0/24"}],
+				"ip_ranges": [{"start_address": "10.0.0.10/24", "end_address": "10.0.0.20/24"}],
+				"ip_addresses": [{"address": "10.0.0.5/24"}]
+			}`,
+			keys:   []string{"10.0.0.5", "10.0.0.10-10.0.0.20", "10.0.0.0/24", "10.0.1.0/24"},
+			failed: []string{},
+		},
+		{
+			name:   "api page",
+			input:  `{"count": 2, "next": null, "results": [{"prefix": "10.0.0.0/24"}, {"description": "empty"}]}`,
+			keys:   []string{"10.0.0.0/24"},
+			failed: []string{"results[1]"},
+		},
+		{
+			name:   "list of objects",
+			input:  `[{"prefix": "2001:db8::/32"}, {"address": "2001:db8::1/64"}]`,
+			keys:   []string{"2001:db8::/32", "2001:db8::1"},
+			failed: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := ParseNetBox(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys, failed := recordKeys(records)
+			if !reflect.DeepEqual(keys, tt.keys) || !reflect.DeepEqual(failed, tt.failed) {
+				t.Errorf("parsed %v and failed %v, want %v and %v", keys, failed, tt.keys, tt.failed)
+			}
+		})
+	}
+
+	records, err := ParseNetBox(strings.NewReader(`{"prefixes": [{"prefix": "10.0.0.0/24", "description": "net", "tags": ["a", {"slug": "b"}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ImportRecord{Source: "prefixes[0]", Key: "10.0.0.0/24", Description: "net", Tags: []string{"a", "b"}}
+	if !reflect.DeepEqual(records, []ImportRecord{want}) {
+		t.Errorf("parsed %+v, want %+v", records, want)
+	}
+	if _, err := ParseNetBox(strings.NewReader(`{`)); errors.Cause(err) != ErrInvalid {
+		t.Errorf("invalid json returned %v", err)
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	input := `prefix,description,tags
+10.0.0.0/24,core network,"a, b;c"
+# a comment
+10.0.0.10-10.0.0.20
+10.0.0.5,host,,extra
+`
+	records, err := ParseCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, failed := recordKeys(records)
+	if want := []string{"10.0.0.0/24", "10.0.0.10-10.0.0.20"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("parsed %v, want %v", keys, want)
+	}
+	if want := []string{"line 5"}; !reflect.DeepEqual(failed, want) {
+		t.Errorf("failed %v, want %v", failed, want)
+	}
+	if want := []string{"a", "b", "c"}; records[0].Description != "core network" || !reflect.DeepEqual(records[0].Tags, want) {
+		t.Errorf("parsed %+v", records[0])
+	}
+}
+
+func TestParseIPRoute(t *testing.T) {
+	input := `default via 192.168.1.1 dev eth0 proto dhcp
+10.0.0.0/24 dev eth1 proto kernel scope link src 10.0.0.1
+blackhole 10.1.0.0/16
+192.168.1.7 via 192.168.1.1 dev eth0
+local 10.0.0.1 dev eth1
+2001:db8::1 dev eth0
+`
+	records, err := ParseIPRoute(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, failed := recordKeys(records)
+	if want := []string{"10.0.0.0/24", "10.1.0.0/16", "192.168.1.7/32", "2001:db8::1/128"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("parsed %v, want %v", keys, want)
+	}
+	if want := []string{"line 1", "line 5"}; !reflect.DeepEqual(failed, want) {
+		t.Errorf("failed %v, want %v", failed, want)
+	}
+	if want := []string{"dev=eth1", "proto=kernel"}; !reflect.DeepEqual(records[1].Tags, want) {
+		t.Errorf("tags %v, want %v", records[1].Tags, want)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"inet.af/netaddr"
+)
+
+// ErrInvariant is returned when the content of the tree violates an
+// invariant of the tree
+var ErrInvariant = errors.New("invariant violated")
+
+// CheckInvariants validates the structure of the tree and its entries
+// against each other and against the policy of the tree:
+//   - the nodes of a family are walked in address order, a parent goes before
+//     its children
+//   - the range index is ordered, balanced and its subtree bounds are exact
+//   - ranges do not overlap, nested ranges are allowed by the policy only
+//   - a prefix is not inside a range unless the policy allows it
+//   - a range has a parent prefix unless the policy allows it
+//
+// The overlaps are computed by comparing every pair of exported entries
+// rather than with the indexes of the tree, such that a fault in an index
+// cannot hide a violation. The first violation is returned
+func (ipam *IpTree[T]) CheckInvariants() error {
+	for _, f := range Families {
+		if err := ipam.checkNodes(f); err != nil {
+			return errors.Wrapf(err, "%s nodes", f)
+		}
+		if err := ipam.rangeIndex(f).check(); err != nil {
+			return errors.Wrapf(err, "%s range index", f)
+		}
+	}
+	return ipam.checkOverlaps()
+}
+
+// checkNodes validates the walk order of the nodes of family f
+func (ipam *IpTree[T]) checkNodes(f Family) error {
+	var prev netaddr.IPPrefix
+	nodes := 0
+	var err error
+	ipam.WalkFamily(f, func(p netaddr.IPPrefix, d *Data[T]) bool {
+		if nodes > 0 && (p.IP().Less(prev.IP()) || p.IP() == prev.IP() && p.Bits() <= prev.Bits()) {
+			err = errors.Wrapf(ErrInvariant, "node %s is walked after node %s", p, prev)
+			return false
+		}
+		if len(d.GetValue()) == 0 {
+			err = errors.Wrapf(ErrInvariant, "node %s has no entries", p)
+			return false
+		}
+		prev = p
+		nodes++
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if size := ipam.GetTree(f).Size(); size != nodes {
+		return errors.Wrapf(ErrInvariant, "size %d, walked %d nodes", size, nodes)
+	}
+	return nil
+}
+
+// checkOverlaps validates every exported range against the other exported
+// ranges and the exported prefixes
+func (ipam *IpTree[T]) checkOverlaps() error {
+	prefixes := []netaddr.IPPrefix{}
+	ranges := []netaddr.IPRange{}
+	for _, e := range ipam.Export() {
+		switch {
+		case strings.Contains(e.Key, "-"):
+			r, err := netaddr.ParseIPRange(e.Key)
+			if err != nil {
+				return errors.Wrapf(ErrInvariant, "range key %s: %v", e.Key, err)
+			}
+			ranges = append(ranges, r)
+		case strings.Contains(e.Key, "/"):
+			p, err := netaddr.ParseIPPrefix(e.Key)
+			if err != nil {
+				return errors.Wrapf(ErrInvariant, "prefix key %s: %v", e.Key, err)
+			}
+			prefixes = append(prefixes, p)
+		}
+	}
+	policy := ipam.GetPolicy()
+	for i, r := range ranges {
+		for _, o := range ranges[i+1:] {
+			switch {
+			case !r.Overlaps(o):
+			case o == r:
+				return errors.Wrapf(ErrInvariant, "range %s is exported twice", r)
+			case rangeContains(o, r) || rangeContains(r, o):
+				if !policy.AllowNestedRanges {
+					return errors.Wrapf(ErrInvariant, "range %s and range %s are nested", r, o)
+				}
+			default:
+				return errors.Wrapf(ErrInvariant, "range %s overlaps range %s", r, o)
+			}
+		}
+		parent := false
+		for _, p := range prefixes {
+			pr := p.Range()
+			switch {
+			case !pr.Overlaps(r):
+			case pr == r && !policy.AllowPrefixEqualRange && !policy.AllowPrefixInRange:
+				return errors.Wrapf(ErrInvariant, "prefix %s equals range %s", p, r)
+			case rangeContains(pr, r):
+				parent = true
+			case rangeContains(r, pr):
+				if !policy.AllowPrefixInRange {
+					return errors.Wrapf(ErrInvariant, "prefix %s is inside range %s", p, r)
+				}
+			default:
+				return errors.Wrapf(ErrInvariant, "prefix %s overlaps range %s", p, r)
+			}
+		}
+		if !parent && !policy.AllowRangeWithoutParent {
+			return errors.Wrapf(ErrInvariant, "range %s has no parent prefix", r)
+		}
+	}
+	return nil
+}
+
+// check validates the order, the balance and the subtree bounds of the
+// nodes of the index
+func (x *rangeIndex[T]) check() error {
+	n := 0
+	var prev *rangeNode[T]
+	var err error
+	x.walk(func(m *rangeNode[T]) bool {
+		if prev != nil && compareRange(prev.r, m.r) >= 0 {
+			err = errors.Wrapf(ErrInvariant, "range %s is walked after range %s", m.r, prev.r)
+			return false
+		}
+		if m.key != m.r.String() {
+			err = errors.Wrapf(ErrInvariant, "range %s has key %s", m.r, m.key)
+			return false
+		}
+		prev = m
+		n++
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if n != x.size {
+		return errors.Wrapf(ErrInvariant, "size %d, walked %d ranges", x.size, n)
+	}
+	_, err = x.root.check()
+	return err
+}
+
+// check validates the subtree of n and returns its height
+func (n *rangeNode[T]) check() (int, error) {
+	if n == nil {
+		return 0, nil
+	}
+	l, err := n.left.check()
+	if err != nil {
+		return 0, err
+	}
+	r, err := n.right.check()
+	if err != nil {
+		return 0, err
+	}
+	if l-r > 1 || r-l > 1 {
+		return 0, errors.Wrapf(ErrInvariant, "range %s has subtrees of height %d and %d", n.r, l, r)
+	}
+	if n.height != 1+max(l, r) {
+		return 0, errors.Wrapf(ErrInvariant, "range %s has height %d, expected %d", n.r, n.height, 1+max(l, r))
+	}
+	maxTo := n.r.To()
+	for _, c := range []*rangeNode[T]{n.left, n.right} {
+		if c != nil && maxTo.Less(c.maxTo) {
+			maxTo = c.maxTo
+		}
+	}
+	if n.maxTo != maxTo {
+		return 0, errors.Wrapf(ErrInvariant, "range %s has bound %s, expected %s", n.r, n.maxTo, maxTo)
+	}
+	return n.height, nil
+}
@@ -0,0 +1,4 @@
+// Package ipampb holds the grpc api of the ipam tree
+package ipampb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative ipam.proto
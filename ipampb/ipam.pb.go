@@ -0,0 +1,1321 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: ipam.proto
+
+package ipampb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Family int32
+
+const (
+	Family_FAMILY_UNSPECIFIED Family = 0
+	Family_FAMILY_IPV4        Family = 1
+	Family_FAMILY_IPV6        Family = 2
+)
+
+// Enum value maps for Family.
+var (
+	Family_name = map[int32]string{
+		0: "FAMILY_UNSPECIFIED",
+		1: "FAMILY_IPV4",
+		2: "FAMILY_IPV6",
+	}
+	Family_value = map[string]int32{
+		"FAMILY_UNSPECIFIED": 0,
+		"FAMILY_IPV4":        1,
+		"FAMILY_IPV6":        2,
+	}
+)
+
+func (x Family) Enum() *Family {
+	p := new(Family)
+	*p = x
+	return p
+}
+
+func (x Family) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Family) Descriptor() protoreflect.EnumDescriptor {
+	return file_ipam_proto_enumTypes[0].Descriptor()
+}
+
+func (Family) Type() protoreflect.EnumType {
+	return &file_ipam_proto_enumTypes[0]
+}
+
+func (x Family) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Family.Descriptor instead.
+func (Family) EnumDescriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{0}
+}
+
+type EntryKind int32
+
+const (
+	EntryKind_ENTRY_KIND_UNSPECIFIED EntryKind = 0
+	EntryKind_ENTRY_KIND_PREFIX      EntryKind = 1
+	EntryKind_ENTRY_KIND_RANGE       EntryKind = 2
+	EntryKind_ENTRY_KIND_ADDRESS     EntryKind = 3
+)
+
+// Enum value maps for EntryKind.
+var (
+	EntryKind_name = map[int32]string{
+		0: "ENTRY_KIND_UNSPECIFIED",
+		1: "ENTRY_KIND_PREFIX",
+		2: "ENTRY_KIND_RANGE",
+		3: "ENTRY_KIND_ADDRESS",
+	}
+	EntryKind_value = map[string]int32{
+		"ENTRY_KIND_UNSPECIFIED": 0,
+		"ENTRY_KIND_PREFIX":      1,
+		"ENTRY_KIND_RANGE":       2,
+		"ENTRY_KIND_ADDRESS":     3,
+	}
+)
+
+func (x EntryKind) Enum() *EntryKind {
+	p := new(EntryKind)
+	*p = x
+	return p
+}
+
+func (x EntryKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EntryKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_ipam_proto_enumTypes[1].Descriptor()
+}
+
+func (EntryKind) Type() protoreflect.EnumType {
+	return &file_ipam_proto_enumTypes[1]
+}
+
+func (x EntryKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EntryKind.Descriptor instead.
+func (EntryKind) EnumDescriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{1}
+}
+
+type DeleteMode int32
+
+const (
+	DeleteMode_DELETE_MODE_UNSPECIFIED DeleteMode = 0
+	DeleteMode_DELETE_MODE_STRICT      DeleteMode = 1
+	DeleteMode_DELETE_MODE_CASCADE     DeleteMode = 2
+	DeleteMode_DELETE_MODE_REPARENT    DeleteMode = 3
+)
+
+// Enum value maps for DeleteMode.
+var (
+	DeleteMode_name = map[int32]string{
+		0: "DELETE_MODE_UNSPECIFIED",
+		1: "DELETE_MODE_STRICT",
+		2: "DELETE_MODE_CASCADE",
+		3: "DELETE_MODE_REPARENT",
+	}
+	DeleteMode_value = map[string]int32{
+		"DELETE_MODE_UNSPECIFIED": 0,
+		"DELETE_MODE_STRICT":      1,
+		"DELETE_MODE_CASCADE":     2,
+		"DELETE_MODE_REPARENT":    3,
+	}
+)
+
+func (x DeleteMode) Enum() *DeleteMode {
+	p := new(DeleteMode)
+	*p = x
+	return p
+}
+
+func (x DeleteMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DeleteMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_ipam_proto_enumTypes[2].Descriptor()
+}
+
+func (DeleteMode) Type() protoreflect.EnumType {
+	return &file_ipam_proto_enumTypes[2]
+}
+
+func (x DeleteMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DeleteMode.Descriptor instead.
+func (DeleteMode) EnumDescriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{2}
+}
+
+// Entry is a prefix, range or ip address stored in the tree
+type Entry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Kind          EntryKind              `protobuf:"varint,3,opt,name=kind,proto3,enum=ipam.v1.EntryKind" json:"kind,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Entry) Reset() {
+	*x = Entry{}
+	mi := &file_ipam_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Entry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Entry) ProtoMessage() {}
+
+func (x *Entry) ProtoReflect() protoreflect.Message {
+	mi := &file_ipam_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Entry.ProtoReflect.Descriptor instead.
+func (*Entry) Descriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Entry) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Entry) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Entry) GetKind() EntryKind {
+	if x != nil {
+		return x.Kind
+	}
+	return EntryKind_ENTRY_KIND_UNSPECIFIED
+}
+
+type AddPrefixRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddPrefixRequest) Reset() {
+	*x = AddPrefixRequest{}
+	mi := &file_ipam_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddPrefixRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddPrefixRequest) ProtoMessage() {}
+
+func (x *AddPrefixRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ipam_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddPrefixRequest.ProtoReflect.Descriptor instead.
+func (*AddPrefixRequest) Descriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AddPrefixRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *AddPrefixRequest) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type AddPrefixResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entry         *Entry                 `protobuf:"bytes,1,opt,name=entry,proto3" json:"entry,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddPrefixResponse) Reset() {
+	*x = AddPrefixResponse{}
+	mi := &file_ipam_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddPrefixResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddPrefixResponse) ProtoMessage() {}
+
+func (x *AddPrefixResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ipam_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddPrefixResponse.ProtoReflect.Descriptor instead.
+func (*AddPrefixResponse) Descriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AddPrefixResponse) GetEntry() *Entry {
+	if x != nil {
+		return x.Entry
+	}
+	return nil
+}
+
+type GetPrefixRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPrefixRequest) Reset() {
+	*x = GetPrefixRequest{}
+	mi := &file_ipam_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPrefixRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPrefixRequest) ProtoMessage() {}
+
+func (x *GetPrefixRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ipam_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPrefixRequest.ProtoReflect.Descriptor instead.
+func (*GetPrefixRequest) Descriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetPrefixRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+type DeletePrefixRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Mode          DeleteMode             `protobuf:"varint,2,opt,name=mode,proto3,enum=ipam.v1.DeleteMode" json:"mode,omitempty"`
+	DryRun        bool                   `protobuf:"varint,3,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletePrefixRequest) Reset() {
+	*x = DeletePrefixRequest{}
+	mi := &file_ipam_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePrefixRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePrefixRequest) ProtoMessage() {}
+
+func (x *DeletePrefixRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ipam_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePrefixRequest.ProtoReflect.Descriptor instead.
+func (*DeletePrefixRequest) Descriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DeletePrefixRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *DeletePrefixRequest) GetMode() DeleteMode {
+	if x != nil {
+		return x.Mode
+	}
+	return DeleteMode_DELETE_MODE_UNSPECIFIED
+}
+
+func (x *DeletePrefixRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+type DeletePrefixResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Deleted       []string               `protobuf:"bytes,1,rep,name=deleted,proto3" json:"deleted,omitempty"`
+	Reparented    []string               `protobuf:"bytes,2,rep,name=reparented,proto3" json:"reparented,omitempty"`
+	Parent        string                 `protobuf:"bytes,3,opt,name=parent,proto3" json:"parent,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletePrefixResponse) Reset() {
+	*x = DeletePrefixResponse{}
+	mi := &file_ipam_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePrefixResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePrefixResponse) ProtoMessage() {}
+
+func (x *DeletePrefixResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ipam_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePrefixResponse.ProtoReflect.Descriptor instead.
+func (*DeletePrefixResponse) Descriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DeletePrefixResponse) GetDeleted() []string {
+	if x != nil {
+		return x.Deleted
+	}
+	return nil
+}
+
+func (x *DeletePrefixResponse) GetReparented() []string {
+	if x != nil {
+		return x.Reparented
+	}
+	return nil
+}
+
+func (x *DeletePrefixResponse) GetParent() string {
+	if x != nil {
+		return x.Parent
+	}
+	return ""
+}
+
+type AddRangeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Range         string                 `protobuf:"bytes,1,opt,name=range,proto3" json:"range,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddRangeRequest) Reset() {
+	*x = AddRangeRequest{}
+	mi := &file_ipam_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddRangeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddRangeRequest) ProtoMessage() {}
+
+func (x *AddRangeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ipam_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddRangeRequest.ProtoReflect.Descriptor instead.
+func (*AddRangeRequest) Descriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AddRangeRequest) GetRange() string {
+	if x != nil {
+		return x.Range
+	}
+	return ""
+}
+
+func (x *AddRangeRequest) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type AddRangeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entry         *Entry                 `protobuf:"bytes,1,opt,name=entry,proto3" json:"entry,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddRangeResponse) Reset() {
+	*x = AddRangeResponse{}
+	mi := &file_ipam_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddRangeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddRangeResponse) ProtoMessage() {}
+
+func (x *AddRangeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ipam_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddRangeResponse.ProtoReflect.Descriptor instead.
+func (*AddRangeResponse) Descriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *AddRangeResponse) GetEntry() *Entry {
+	if x != nil {
+		return x.Entry
+	}
+	return nil
+}
+
+type GetRangeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Range         string                 `protobuf:"bytes,1,opt,name=range,proto3" json:"range,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRangeRequest) Reset() {
+	*x = GetRangeRequest{}
+	mi := &file_ipam_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRangeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRangeRequest) ProtoMessage() {}
+
+func (x *GetRangeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ipam_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRangeRequest.ProtoReflect.Descriptor instead.
+func (*GetRangeRequest) Descriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetRangeRequest) GetRange() string {
+	if x != nil {
+		return x.Range
+	}
+	return ""
+}
+
+type DeleteRangeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Range         string                 `protobuf:"bytes,1,opt,name=range,proto3" json:"range,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRangeRequest) Reset() {
+	*x = DeleteRangeRequest{}
+	mi := &file_ipam_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRangeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRangeRequest) ProtoMessage() {}
+
+func (x *DeleteRangeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ipam_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRangeRequest.ProtoReflect.Descriptor instead.
+func (*DeleteRangeRequest) Descriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DeleteRangeRequest) GetRange() string {
+	if x != nil {
+		return x.Range
+	}
+	return ""
+}
+
+type DeleteRangeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRangeResponse) Reset() {
+	*x = DeleteRangeResponse{}
+	mi := &file_ipam_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRangeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRangeResponse) ProtoMessage() {}
+
+func (x *DeleteRangeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ipam_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRangeResponse.ProtoReflect.Descriptor instead.
+func (*DeleteRangeResponse) Descriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{10}
+}
+
+// AllocateRequest allocates the first free ip address of the prefix or
+// range in from, or the specific ip address in address
+type AllocateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	From          string                 `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Value         string                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AllocateRequest) Reset() {
+	*x = AllocateRequest{}
+	mi := &file_ipam_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AllocateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AllocateRequest) ProtoMessage() {}
+
+func (x *AllocateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ipam_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AllocateRequest.ProtoReflect.Descriptor instead.
+func (*AllocateRequest) Descriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *AllocateRequest) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *AllocateRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *AllocateRequest) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type AllocateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entry         *Entry                 `protobuf:"bytes,1,opt,name=entry,proto3" json:"entry,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AllocateResponse) Reset() {
+	*x = AllocateResponse{}
+	mi := &file_ipam_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AllocateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AllocateResponse) ProtoMessage() {}
+
+func (x *AllocateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ipam_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AllocateResponse.ProtoReflect.Descriptor instead.
+func (*AllocateResponse) Descriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *AllocateResponse) GetEntry() *Entry {
+	if x != nil {
+		return x.Entry
+	}
+	return nil
+}
+
+type ReleaseRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReleaseRequest) Reset() {
+	*x = ReleaseRequest{}
+	mi := &file_ipam_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReleaseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseRequest) ProtoMessage() {}
+
+func (x *ReleaseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ipam_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseRequest.ProtoReflect.Descriptor instead.
+func (*ReleaseRequest) Descriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ReleaseRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+type ReleaseResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReleaseResponse) Reset() {
+	*x = ReleaseResponse{}
+	mi := &file_ipam_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReleaseResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseResponse) ProtoMessage() {}
+
+func (x *ReleaseResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ipam_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseResponse.ProtoReflect.Descriptor instead.
+func (*ReleaseResponse) Descriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{14}
+}
+
+// LookupRequest gets the entry of a prefix, range or ip address by its key
+type LookupRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupRequest) Reset() {
+	*x = LookupRequest{}
+	mi := &file_ipam_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupRequest) ProtoMessage() {}
+
+func (x *LookupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ipam_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupRequest.ProtoReflect.Descriptor instead.
+func (*LookupRequest) Descriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *LookupRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type LongestMatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LongestMatchRequest) Reset() {
+	*x = LongestMatchRequest{}
+	mi := &file_ipam_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LongestMatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LongestMatchRequest) ProtoMessage() {}
+
+func (x *LongestMatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ipam_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LongestMatchRequest.ProtoReflect.Descriptor instead.
+func (*LongestMatchRequest) Descriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *LongestMatchRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+// LongestMatchResponse holds the most specific prefix that holds the ip
+// address and the prefixes, ranges and allocated address that hold it
+// ordered from the least to the most specific
+type LongestMatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Entries       []*Entry               `protobuf:"bytes,2,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LongestMatchResponse) Reset() {
+	*x = LongestMatchResponse{}
+	mi := &file_ipam_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LongestMatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LongestMatchResponse) ProtoMessage() {}
+
+func (x *LongestMatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ipam_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LongestMatchResponse.ProtoReflect.Descriptor instead.
+func (*LongestMatchResponse) Descriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *LongestMatchResponse) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *LongestMatchResponse) GetEntries() []*Entry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type ListEntriesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Family        Family                 `protobuf:"varint,1,opt,name=family,proto3,enum=ipam.v1.Family" json:"family,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListEntriesRequest) Reset() {
+	*x = ListEntriesRequest{}
+	mi := &file_ipam_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListEntriesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListEntriesRequest) ProtoMessage() {}
+
+func (x *ListEntriesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ipam_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListEntriesRequest.ProtoReflect.Descriptor instead.
+func (*ListEntriesRequest) Descriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ListEntriesRequest) GetFamily() Family {
+	if x != nil {
+		return x.Family
+	}
+	return Family_FAMILY_UNSPECIFIED
+}
+
+type ListEntriesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*Entry               `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListEntriesResponse) Reset() {
+	*x = ListEntriesResponse{}
+	mi := &file_ipam_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListEntriesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListEntriesResponse) ProtoMessage() {}
+
+func (x *ListEntriesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ipam_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListEntriesResponse.ProtoReflect.Descriptor instead.
+func (*ListEntriesResponse) Descriptor() ([]byte, []int) {
+	return file_ipam_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ListEntriesResponse) GetEntries() []*Entry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+var File_ipam_proto protoreflect.FileDescriptor
+
+const file_ipam_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"ipam.proto\x12\aipam.v1\"W\n" +
+	"\x05Entry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\x12&\n" +
+	"\x04kind\x18\x03 \x01(\x0e2\x12.ipam.v1.EntryKindR\x04kind\"@\n" +
+	"\x10AddPrefixRequest\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\"9\n" +
+	"\x11AddPrefixResponse\x12$\n" +
+	"\x05entry\x18\x01 \x01(\v2\x0e.ipam.v1.EntryR\x05entry\"*\n" +
+	"\x10GetPrefixRequest\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\"o\n" +
+	"\x13DeletePrefixRequest\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12'\n" +
+	"\x04mode\x18\x02 \x01(\x0e2\x13.ipam.v1.DeleteModeR\x04mode\x12\x17\n" +
+	"\adry_run\x18\x03 \x01(\bR\x06dryRun\"h\n" +
+	"\x14DeletePrefixResponse\x12\x18\n" +
+	"\adeleted\x18\x01 \x03(\tR\adeleted\x12\x1e\n" +
+	"\n" +
+	"reparented\x18\x02 \x03(\tR\n" +
+	"reparented\x12\x16\n" +
+	"\x06parent\x18\x03 \x01(\tR\x06parent\"=\n" +
+	"\x0fAddRangeRequest\x12\x14\n" +
+	"\x05range\x18\x01 \x01(\tR\x05range\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\"8\n" +
+	"\x10AddRangeResponse\x12$\n" +
+	"\x05entry\x18\x01 \x01(\v2\x0e.ipam.v1.EntryR\x05entry\"'\n" +
+	"\x0fGetRangeRequest\x12\x14\n" +
+	"\x05range\x18\x01 \x01(\tR\x05range\"*\n" +
+	"\x12DeleteRangeRequest\x12\x14\n" +
+	"\x05range\x18\x01 \x01(\tR\x05range\"\x15\n" +
+	"\x13DeleteRangeResponse\"U\n" +
+	"\x0fAllocateRequest\x12\x12\n" +
+	"\x04from\x18\x01 \x01(\tR\x04from\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\tR\x05value\"8\n" +
+	"\x10AllocateResponse\x12$\n" +
+	"\x05entry\x18\x01 \x01(\v2\x0e.ipam.v1.EntryR\x05entry\"*\n" +
+	"\x0eReleaseRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\"\x11\n" +
+	"\x0fReleaseResponse\"!\n" +
+	"\rLookupRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\"/\n" +
+	"\x13LongestMatchRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\"X\n" +
+	"\x14LongestMatchResponse\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12(\n" +
+	"\aentries\x18\x02 \x03(\v2\x0e.ipam.v1.EntryR\aentries\"=\n" +
+	"\x12ListEntriesRequest\x12'\n" +
+	"\x06family\x18\x01 \x01(\x0e2\x0f.ipam.v1.FamilyR\x06family\"?\n" +
+	"\x13ListEntriesResponse\x12(\n" +
+	"\aentries\x18\x01 \x03(\v2\x0e.ipam.v1.EntryR\aentries*B\n" +
+	"\x06Family\x12\x16\n" +
+	"\x12FAMILY_UNSPECIFIED\x10\x00\x12\x0f\n" +
+	"\vFAMILY_IPV4\x10\x01\x12\x0f\n" +
+	"\vFAMILY_IPV6\x10\x02*l\n" +
+	"\tEntryKind\x12\x1a\n" +
+	"\x16ENTRY_KIND_UNSPECIFIED\x10\x00\x12\x15\n" +
+	"\x11ENTRY_KIND_PREFIX\x10\x01\x12\x14\n" +
+	"\x10ENTRY_KIND_RANGE\x10\x02\x12\x16\n" +
+	"\x12ENTRY_KIND_ADDRESS\x10\x03*t\n" +
+	"\n" +
+	"DeleteMode\x12\x1b\n" +
+	"\x17DELETE_MODE_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12DELETE_MODE_STRICT\x10\x01\x12\x17\n" +
+	"\x13DELETE_MODE_CASCADE\x10\x02\x12\x18\n" +
+	"\x14DELETE_MODE_REPARENT\x10\x032\xd8\x05\n" +
+	"\x04Ipam\x12B\n" +
+	"\tAddPrefix\x12\x19.ipam.v1.AddPrefixRequest\x1a\x1a.ipam.v1.AddPrefixResponse\x126\n" +
+	"\tGetPrefix\x12\x19.ipam.v1.GetPrefixRequest\x1a\x0e.ipam.v1.Entry\x12K\n" +
+	"\fDeletePrefix\x12\x1c.ipam.v1.DeletePrefixRequest\x1a\x1d.ipam.v1.DeletePrefixResponse\x12?\n" +
+	"\bAddRange\x12\x18.ipam.v1.AddRangeRequest\x1a\x19.ipam.v1.AddRangeResponse\x124\n" +
+	"\bGetRange\x12\x18.ipam.v1.GetRangeRequest\x1a\x0e.ipam.v1.Entry\x12H\n" +
+	"\vDeleteRange\x12\x1b.ipam.v1.DeleteRangeRequest\x1a\x1c.ipam.v1.DeleteRangeResponse\x12?\n" +
+	"\bAllocate\x12\x18.ipam.v1.AllocateRequest\x1a\x19.ipam.v1.AllocateResponse\x12<\n" +
+	"\aRelease\x12\x17.ipam.v1.ReleaseRequest\x1a\x18.ipam.v1.ReleaseResponse\x120\n" +
+	"\x06Lookup\x12\x16.ipam.v1.LookupRequest\x1a\x0e.ipam.v1.Entry\x12K\n" +
+	"\fLongestMatch\x12\x1c.ipam.v1.LongestMatchRequest\x1a\x1d.ipam.v1.LongestMatchResponse\x12H\n" +
+	"\vListEntries\x12\x1b.ipam.v1.ListEntriesRequest\x1a\x1c.ipam.v1.ListEntriesResponseB Z\x1egithub.com/henderiw/lpm/ipampbb\x06proto3"
+
+var (
+	file_ipam_proto_rawDescOnce sync.Once
+	file_ipam_proto_rawDescData []byte
+)
+
+func file_ipam_proto_rawDescGZIP() []byte {
+	file_ipam_proto_rawDescOnce.Do(func() {
+		file_ipam_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_ipam_proto_rawDesc), len(file_ipam_proto_rawDesc)))
+	})
+	return file_ipam_proto_rawDescData
+}
+
+var file_ipam_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_ipam_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_ipam_proto_goTypes = []any{
+	(Family)(0),                  // 0: ipam.v1.Family
+	(EntryKind)(0),               // 1: ipam.v1.EntryKind
+	(DeleteMode)(0),              // 2: ipam.v1.DeleteMode
+	(*Entry)(nil),                // 3: ipam.v1.Entry
+	(*AddPrefixRequest)(nil),     // 4: ipam.v1.AddPrefixRequest
+	(*AddPrefixResponse)(nil),    // 5: ipam.v1.AddPrefixResponse
+	(*GetPrefixRequest)(nil),     // 6: ipam.v1.GetPrefixRequest
+	(*DeletePrefixRequest)(nil),  // 7: ipam.v1.DeletePrefixRequest
+	(*DeletePrefixResponse)(nil), // 8: ipam.v1.DeletePrefixResponse
+	(*AddRangeRequest)(nil),      // 9: ipam.v1.AddRangeRequest
+	(*AddRangeResponse)(nil),     // 10: ipam.v1.AddRangeResponse
+	(*GetRangeRequest)(nil),      // 11: ipam.v1.GetRangeRequest
+	(*DeleteRangeRequest)(nil),   // 12: ipam.v1.DeleteRangeRequest
+	(*DeleteRangeResponse)(nil),  // 13: ipam.v1.DeleteRangeResponse
+	(*AllocateRequest)(nil),      // 14: ipam.v1.AllocateRequest
+	(*AllocateResponse)(nil),     // 15: ipam.v1.AllocateResponse
+	(*ReleaseRequest)(nil),       // 16: ipam.v1.ReleaseRequest
+	(*ReleaseResponse)(nil),      // 17: ipam.v1.ReleaseResponse
+	(*LookupRequest)(nil),        // 18: ipam.v1.LookupRequest
+	(*LongestMatchRequest)(nil),  // 19: ipam.v1.LongestMatchRequest
+	(*LongestMatchResponse)(nil), // 20: ipam.v1.LongestMatchResponse
+	(*ListEntriesRequest)(nil),   // 21: ipam.v1.ListEntriesRequest
+	(*ListEntriesResponse)(nil),  // 22: ipam.v1.ListEntriesResponse
+}
+var file_ipam_proto_depIdxs = []int32{
+	1,  // 0: ipam.v1.Entry.kind:type_name -> ipam.v1.EntryKind
+	3,  // 1: ipam.v1.AddPrefixResponse.entry:type_name -> ipam.v1.Entry
+	2,  // 2: ipam.v1.DeletePrefixRequest.mode:type_name -> ipam.v1.DeleteMode
+	3,  // 3: ipam.v1.AddRangeResponse.entry:type_name -> ipam.v1.Entry
+	3,  // 4: ipam.v1.AllocateResponse.entry:type_name -> ipam.v1.Entry
+	3,  // 5: ipam.v1.LongestMatchResponse.entries:type_name -> ipam.v1.Entry
+	0,  // 6: ipam.v1.ListEntriesRequest.family:type_name -> ipam.v1.Family
+	3,  // 7: ipam.v1.ListEntriesResponse.entries:type_name -> ipam.v1.Entry
+	4,  // 8: ipam.v1.Ipam.AddPrefix:input_type -> ipam.v1.AddPrefixRequest
+	6,  // 9: ipam.v1.Ipam.GetPrefix:input_type -> ipam.v1.GetPrefixRequest
+	7,  // 10: ipam.v1.Ipam.DeletePrefix:input_type -> ipam.v1.DeletePrefixRequest
+	9,  // 11: ipam.v1.Ipam.AddRange:input_type -> ipam.v1.AddRangeRequest
+	11, // 12: ipam.v1.Ipam.GetRange:input_type -> ipam.v1.GetRangeRequest
+	12, // 13: ipam.v1.Ipam.DeleteRange:input_type -> ipam.v1.DeleteRangeRequest
+	14, // 14: ipam.v1.Ipam.Allocate:input_type -> ipam.v1.AllocateRequest
+	16, // 15: ipam.v1.Ipam.Release:input_type -> ipam.v1.ReleaseRequest
+	18, // 16: ipam.v1.Ipam.Lookup:input_type -> ipam.v1.LookupRequest
+	19, // 17: ipam.v1.Ipam.LongestMatch:input_type -> ipam.v1.LongestMatchRequest
+	21, // 18: ipam.v1.Ipam.ListEntries:input_type -> ipam.v1.ListEntriesRequest
+	5,  // 19: ipam.v1.Ipam.AddPrefix:output_type -> ipam.v1.AddPrefixResponse
+	3,  // 20: ipam.v1.Ipam.GetPrefix:output_type -> ipam.v1.Entry
+	8,  // 21: ipam.v1.Ipam.DeletePrefix:output_type -> ipam.v1.DeletePrefixResponse
+	10, // 22: ipam.v1.Ipam.AddRange:output_type -> ipam.v1.AddRangeResponse
+	3,  // 23: ipam.v1.Ipam.GetRange:output_type -> ipam.v1.Entry
+	13, // 24: ipam.v1.Ipam.DeleteRange:output_type -> ipam.v1.DeleteRangeResponse
+	15, // 25: ipam.v1.Ipam.Allocate:output_type -> ipam.v1.AllocateResponse
+	17, // 26: ipam.v1.Ipam.Release:output_type -> ipam.v1.ReleaseResponse
+	3,  // 27: ipam.v1.Ipam.Lookup:output_type -> ipam.v1.Entry
+	20, // 28: ipam.v1.Ipam.LongestMatch:output_type -> ipam.v1.LongestMatchResponse
+	22, // 29: ipam.v1.Ipam.ListEntries:output_type -> ipam.v1.ListEntriesResponse
+	19, // [19:30] is the sub-list for method output_type
+	8,  // [8:19] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_ipam_proto_init() }
+func file_ipam_proto_init() {
+	if File_ipam_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ipam_proto_rawDesc), len(file_ipam_proto_rawDesc)),
+			NumEnums:      3,
+			NumMessages:   20,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_ipam_proto_goTypes,
+		DependencyIndexes: file_ipam_proto_depIdxs,
+		EnumInfos:         file_ipam_proto_enumTypes,
+		MessageInfos:      file_ipam_proto_msgTypes,
+	}.Build()
+	File_ipam_proto = out.File
+	file_ipam_proto_goTypes = nil
+	file_ipam_proto_depIdxs = nil
+}
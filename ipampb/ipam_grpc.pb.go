@@ -0,0 +1,507 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: ipam.proto
+
+package ipampb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Ipam_AddPrefix_FullMethodName    = "/ipam.v1.Ipam/AddPrefix"
+	Ipam_GetPrefix_FullMethodName    = "/ipam.v1.Ipam/GetPrefix"
+	Ipam_DeletePrefix_FullMethodName = "/ipam.v1.Ipam/DeletePrefix"
+	Ipam_AddRange_FullMethodName     = "/ipam.v1.Ipam/AddRange"
+	Ipam_GetRange_FullMethodName     = "/ipam.v1.Ipam/GetRange"
+	Ipam_DeleteRange_FullMethodName  = "/ipam.v1.Ipam/DeleteRange"
+	Ipam_Allocate_FullMethodName     = "/ipam.v1.Ipam/Allocate"
+	Ipam_Release_FullMethodName      = "/ipam.v1.Ipam/Release"
+	Ipam_Lookup_FullMethodName       = "/ipam.v1.Ipam/Lookup"
+	Ipam_LongestMatch_FullMethodName = "/ipam.v1.Ipam/LongestMatch"
+	Ipam_ListEntries_FullMethodName  = "/ipam.v1.Ipam/ListEntries"
+)
+
+// IpamClient is the client API for Ipam service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Ipam exposes an ipam tree holding prefixes, ranges and allocated ip
+// addresses with a string value
+type IpamClient interface {
+	AddPrefix(ctx context.Context, in *AddPrefixRequest, opts ...grpc.CallOption) (*AddPrefixResponse, error)
+	GetPrefix(ctx context.Context, in *GetPrefixRequest, opts ...grpc.CallOption) (*Entry, error)
+	DeletePrefix(ctx context.Context, in *DeletePrefixRequest, opts ...grpc.CallOption) (*DeletePrefixResponse, error)
+	AddRange(ctx context.Context, in *AddRangeRequest, opts ...grpc.CallOption) (*AddRangeResponse, error)
+	GetRange(ctx context.Context, in *GetRangeRequest, opts ...grpc.CallOption) (*Entry, error)
+	DeleteRange(ctx context.Context, in *DeleteRangeRequest, opts ...grpc.CallOption) (*DeleteRangeResponse, error)
+	Allocate(ctx context.Context, in *AllocateRequest, opts ...grpc.CallOption) (*AllocateResponse, error)
+	Release(ctx context.Context, in *ReleaseRequest, opts ...grpc.CallOption) (*ReleaseResponse, error)
+	Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*Entry, error)
+	LongestMatch(ctx context.Context, in *LongestMatchRequest, opts ...grpc.CallOption) (*LongestMatchResponse, error)
+	ListEntries(ctx context.Context, in *ListEntriesRequest, opts ...grpc.CallOption) (*ListEntriesResponse, error)
+}
+
+type ipamClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewIpamClient(cc grpc.ClientConnInterface) IpamClient {
+	return &ipamClient{cc}
+}
+
+func (c *ipamClient) AddPrefix(ctx context.Context, in *AddPrefixRequest, opts ...grpc.CallOption) (*AddPrefixResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddPrefixResponse)
+	err := c.cc.Invoke(ctx, Ipam_AddPrefix_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ipamClient) GetPrefix(ctx context.Context, in *GetPrefixRequest, opts ...grpc.CallOption) (*Entry, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Entry)
+	err := c.cc.Invoke(ctx, Ipam_GetPrefix_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ipamClient) DeletePrefix(ctx context.Context, in *DeletePrefixRequest, opts ...grpc.CallOption) (*DeletePrefixResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeletePrefixResponse)
+	err := c.cc.Invoke(ctx, Ipam_DeletePrefix_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ipamClient) AddRange(ctx context.Context, in *AddRangeRequest, opts ...grpc.CallOption) (*AddRangeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddRangeResponse)
+	err := c.cc.Invoke(ctx, Ipam_AddRange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ipamClient) GetRange(ctx context.Context, in *GetRangeRequest, opts ...grpc.CallOption) (*Entry, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Entry)
+	err := c.cc.Invoke(ctx, Ipam_GetRange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ipamClient) DeleteRange(ctx context.Context, in *DeleteRangeRequest, opts ...grpc.CallOption) (*DeleteRangeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteRangeResponse)
+	err := c.cc.Invoke(ctx, Ipam_DeleteRange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ipamClient) Allocate(ctx context.Context, in *AllocateRequest, opts ...grpc.CallOption) (*AllocateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AllocateResponse)
+	err := c.cc.Invoke(ctx, Ipam_Allocate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ipamClient) Release(ctx context.Context, in *ReleaseRequest, opts ...grpc.CallOption) (*ReleaseResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReleaseResponse)
+	err := c.cc.Invoke(ctx, Ipam_Release_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ipamClient) Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*Entry, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Entry)
+	err := c.cc.Invoke(ctx, Ipam_Lookup_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ipamClient) LongestMatch(ctx context.Context, in *LongestMatchRequest, opts ...grpc.CallOption) (*LongestMatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LongestMatchResponse)
+	err := c.cc.Invoke(ctx, Ipam_LongestMatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ipamClient) ListEntries(ctx context.Context, in *ListEntriesRequest, opts ...grpc.CallOption) (*ListEntriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListEntriesResponse)
+	err := c.cc.Invoke(ctx, Ipam_ListEntries_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// IpamServer is the server API for Ipam service.
+// All implementations must embed UnimplementedIpamServer
+// for forward compatibility.
+//
+// Ipam exposes an ipam tree holding prefixes, ranges and allocated ip
+// addresses with a string value
+type IpamServer interface {
+	AddPrefix(context.Context, *AddPrefixRequest) (*AddPrefixResponse, error)
+	GetPrefix(context.Context, *GetPrefixRequest) (*Entry, error)
+	DeletePrefix(context.Context, *DeletePrefixRequest) (*DeletePrefixResponse, error)
+	AddRange(context.Context, *AddRangeRequest) (*AddRangeResponse, error)
+	GetRange(context.Context, *GetRangeRequest) (*Entry, error)
+	DeleteRange(context.Context, *DeleteRangeRequest) (*DeleteRangeResponse, error)
+	Allocate(context.Context, *AllocateRequest) (*AllocateResponse, error)
+	Release(context.Context, *ReleaseRequest) (*ReleaseResponse, error)
+	Lookup(context.Context, *LookupRequest) (*Entry, error)
+	LongestMatch(context.Context, *LongestMatchRequest) (*LongestMatchResponse, error)
+	ListEntries(context.Context, *ListEntriesRequest) (*ListEntriesResponse, error)
+	mustEmbedUnimplementedIpamServer()
+}
+
+// UnimplementedIpamServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedIpamServer struct{}
+
+func (UnimplementedIpamServer) AddPrefix(context.Context, *AddPrefixRequest) (*AddPrefixResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddPrefix not implemented")
+}
+func (UnimplementedIpamServer) GetPrefix(context.Context, *GetPrefixRequest) (*Entry, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPrefix not implemented")
+}
+func (UnimplementedIpamServer) DeletePrefix(context.Context, *DeletePrefixRequest) (*DeletePrefixResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeletePrefix not implemented")
+}
+func (UnimplementedIpamServer) AddRange(context.Context, *AddRangeRequest) (*AddRangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddRange not implemented")
+}
+func (UnimplementedIpamServer) GetRange(context.Context, *GetRangeRequest) (*Entry, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRange not implemented")
+}
+func (UnimplementedIpamServer) DeleteRange(context.Context, *DeleteRangeRequest) (*DeleteRangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteRange not implemented")
+}
+func (UnimplementedIpamServer) Allocate(context.Context, *AllocateRequest) (*AllocateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Allocate not implemented")
+}
+func (UnimplementedIpamServer) Release(context.Context, *ReleaseRequest) (*ReleaseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Release not implemented")
+}
+func (UnimplementedIpamServer) Lookup(context.Context, *LookupRequest) (*Entry, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Lookup not implemented")
+}
+func (UnimplementedIpamServer) LongestMatch(context.Context, *LongestMatchRequest) (*LongestMatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LongestMatch not implemented")
+}
+func (UnimplementedIpamServer) ListEntries(context.Context, *ListEntriesRequest) (*ListEntriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListEntries not implemented")
+}
+func (UnimplementedIpamServer) mustEmbedUnimplementedIpamServer() {}
+func (UnimplementedIpamServer) testEmbeddedByValue()              {}
+
+// UnsafeIpamServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to IpamServer will
+// result in compilation errors.
+type UnsafeIpamServer interface {
+	mustEmbedUnimplementedIpamServer()
+}
+
+func RegisterIpamServer(s grpc.ServiceRegistrar, srv IpamServer) {
+	// If the following call pancis, it indicates UnimplementedIpamServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Ipam_ServiceDesc, srv)
+}
+
+func _Ipam_AddPrefix_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddPrefixRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IpamServer).AddPrefix(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ipam_AddPrefix_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IpamServer).AddPrefix(ctx, req.(*AddPrefixRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ipam_GetPrefix_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPrefixRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IpamServer).GetPrefix(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ipam_GetPrefix_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IpamServer).GetPrefix(ctx, req.(*GetPrefixRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ipam_DeletePrefix_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeletePrefixRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IpamServer).DeletePrefix(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ipam_DeletePrefix_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IpamServer).DeletePrefix(ctx, req.(*DeletePrefixRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ipam_AddRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IpamServer).AddRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ipam_AddRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IpamServer).AddRange(ctx, req.(*AddRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ipam_GetRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IpamServer).GetRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ipam_GetRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IpamServer).GetRange(ctx, req.(*GetRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ipam_DeleteRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IpamServer).DeleteRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ipam_DeleteRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IpamServer).DeleteRange(ctx, req.(*DeleteRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ipam_Allocate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AllocateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IpamServer).Allocate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ipam_Allocate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IpamServer).Allocate(ctx, req.(*AllocateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ipam_Release_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IpamServer).Release(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ipam_Release_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IpamServer).Release(ctx, req.(*ReleaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ipam_Lookup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IpamServer).Lookup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ipam_Lookup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IpamServer).Lookup(ctx, req.(*LookupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ipam_LongestMatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LongestMatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IpamServer).LongestMatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ipam_LongestMatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IpamServer).LongestMatch(ctx, req.(*LongestMatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ipam_ListEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListEntriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IpamServer).ListEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ipam_ListEntries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IpamServer).ListEntries(ctx, req.(*ListEntriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Ipam_ServiceDesc is the grpc.ServiceDesc for Ipam service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Ipam_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ipam.v1.Ipam",
+	HandlerType: (*IpamServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddPrefix",
+			Handler:    _Ipam_AddPrefix_Handler,
+		},
+		{
+			MethodName: "GetPrefix",
+			Handler:    _Ipam_GetPrefix_Handler,
+		},
+		{
+			MethodName: "DeletePrefix",
+			Handler:    _Ipam_DeletePrefix_Handler,
+		},
+		{
+			MethodName: "AddRange",
+			Handler:    _Ipam_AddRange_Handler,
+		},
+		{
+			MethodName: "GetRange",
+			Handler:    _Ipam_GetRange_Handler,
+		},
+		{
+			MethodName: "DeleteRange",
+			Handler:    _Ipam_DeleteRange_Handler,
+		},
+		{
+			MethodName: "Allocate",
+			Handler:    _Ipam_Allocate_Handler,
+		},
+		{
+			MethodName: "Release",
+			Handler:    _Ipam_Release_Handler,
+		},
+		{
+			MethodName: "Lookup",
+			Handler:    _Ipam_Lookup_Handler,
+		},
+		{
+			MethodName: "LongestMatch",
+			Handler:    _Ipam_LongestMatch_Handler,
+		},
+		{
+			MethodName: "ListEntries",
+			Handler:    _Ipam_ListEntries_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "ipam.proto",
+}
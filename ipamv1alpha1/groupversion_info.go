@@ -0,0 +1,21 @@
+// Package ipamv1alpha1 holds the custom resources that declare the
+// prefixes, ranges and ip address claims of the ipam tree
+// +kubebuilder:object:generate=true
+// +groupName=ipam.henderiw.io
+package ipamv1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is the group and version of the ipam resources
+	GroupVersion = schema.GroupVersion{Group: "ipam.henderiw.io", Version: "v1alpha1"}
+
+	// SchemeBuilder adds the ipam resources to a scheme
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds the ipam resources to a scheme
+	AddToScheme = SchemeBuilder.AddToScheme
+)
@@ -0,0 +1,120 @@
+package ipamv1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConditionTypeReady reports if the prefix, range or claim is stored in the
+// ipam tree, the reason of a false condition is the reason of the pre-check
+// failure, e.g. overlap or inside-range
+const ConditionTypeReady = "Ready"
+
+// IPStatus is the observed state of a prefix, range or claim
+type IPStatus struct {
+	// ObservedGeneration is the generation of the spec that was reconciled
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions of the resource
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// IPPrefixSpec declares a prefix of the ipam tree
+type IPPrefixSpec struct {
+	// Prefix in cidr notation, e.g. 10.0.0.0/24
+	Prefix string `json:"prefix"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// IPPrefix is a prefix stored in the ipam tree
+type IPPrefix struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IPPrefixSpec `json:"spec,omitempty"`
+	Status IPStatus     `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// IPPrefixList is a list of prefixes
+type IPPrefixList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IPPrefix `json:"items"`
+}
+
+// IPRangeSpec declares a range of the ipam tree
+type IPRangeSpec struct {
+	// Range of ip addresses, e.g. 10.0.0.10-10.0.0.100
+	Range string `json:"range"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// IPRange is a range stored in the ipam tree
+type IPRange struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IPRangeSpec `json:"spec,omitempty"`
+	Status IPStatus    `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// IPRangeList is a list of ranges
+type IPRangeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IPRange `json:"items"`
+}
+
+// IPClaimSpec declares an ip address that is allocated from a prefix or
+// range of the ipam tree
+type IPClaimSpec struct {
+	// From is the prefix or range the address is allocated from
+	From string `json:"from"`
+	// Address claims a specific ip address, the first free address of From
+	// is allocated when it is empty
+	// +optional
+	Address string `json:"address,omitempty"`
+}
+
+// IPClaimStatus is the observed state of a claim
+type IPClaimStatus struct {
+	IPStatus `json:",inline"`
+	// Address is the ip address allocated for the claim
+	Address string `json:"address,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Address",type=string,JSONPath=`.status.address`
+
+// IPClaim is an ip address allocated from the ipam tree
+type IPClaim struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IPClaimSpec   `json:"spec,omitempty"`
+	Status IPClaimStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// IPClaimList is a list of claims
+type IPClaimList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IPClaim `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(
+		&IPPrefix{}, &IPPrefixList{},
+		&IPRange{}, &IPRangeList{},
+		&IPClaim{}, &IPClaimList{},
+	)
+}
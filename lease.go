@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"inet.af/netaddr"
+)
+
+// Lease is an allocated ip address that is released when it is not renewed
+// by its owner before it expires
+type Lease struct {
+	IP     string
+	Owner  string
+	TTL    time.Duration
+	Expiry time.Time
+}
+
+// leaseTable holds the leases of a tree by ip address, it is shared by the
+// views of the tree
+type leaseTable struct {
+	m      sync.Mutex
+	leases map[string]*Lease
+}
+
+func newLeaseTable() *leaseTable {
+	return &leaseTable{
+		leases: map[string]*Lease{},
+	}
+}
+
+func (lt *leaseTable) add(l *Lease) {
+	lt.m.Lock()
+	defer lt.m.Unlock()
+	lt.leases[l.IP] = l
+}
+
+// get returns the lease of ip address ip, a dummy tree has no leases
+func (lt *leaseTable) get(ip string) (*Lease, bool) {
+	if lt == nil {
+		return nil, false
+	}
+	lt.m.Lock()
+	defer lt.m.Unlock()
+	l, ok := lt.leases[ip]
+	return l, ok
+}
+
+// delete removes the lease of ip address ip, a dummy tree has no leases
+func (lt *leaseTable) delete(ip string) {
+	if lt == nil {
+		return
+	}
+	lt.m.Lock()
+	defer lt.m.Unlock()
+	delete(lt.leases, ip)
+}
+
+// AllocateIPWithLease allocates the first free ip address of the prefix or
+// range from like AllocateIP and leases it to owner for ttl
+func (ipam *IpTree[T]) AllocateIPWithLease(from string, value T, owner string, ttl time.Duration) (string, error) {
+	if err := ipam.checkLease(owner, ttl); err != nil {
+		return "", err
+	}
+	ip, err := ipam.AllocateIP(from, value)
+	if err != nil {
+		return "", err
+	}
+	ipam.addLease(ip, owner, ttl)
+	return ip, nil
+}
+
+// ClaimIPWithLease claims ip address ip like ClaimIP and leases it to owner
+// for ttl
+func (ipam *IpTree[T]) ClaimIPWithLease(ip string, value T, owner string, ttl time.Duration) error {
+	if err := ipam.checkLease(owner, ttl); err != nil {
+		return err
+	}
+	ip, _, err := ipam.tree(ip)
+	if err != nil {
+		return err
+	}
+	if err := ipam.ClaimIP(ip, value); err != nil {
+		return err
+	}
+	ipam.addLease(ip, owner, ttl)
+	return nil
+}
+
+func (ipam *IpTree[T]) checkLease(owner string, ttl time.Duration) error {
+	if ipam.leases == nil {
+		return errors.New("tree does not support leases")
+	}
+	if owner == "" || ttl <= 0 {
+		return errors.Wrapf(ErrInvalid, "lease needs an owner and a positive ttl, got %q and %s", owner, ttl)
+	}
+	return nil
+}
+
+func (ipam *IpTree[T]) addLease(ip, owner string, ttl time.Duration) {
+	ipam.leases.add(&Lease{
+		IP:     ip,
+		Owner:  owner,
+		TTL:    ttl,
+		Expiry: ipam.now().Add(ttl),
+	})
+}
+
+// Renew extends the leases of owner by their ttl from now, ErrNotFound is
+// returned when the owner has no leases
+func (ipam *IpTree[T]) Renew(owner string) error {
+	if ipam.leases == nil {
+		return errors.New("tree does not support leases")
+	}
+	now := ipam.now()
+	lt := ipam.leases
+	lt.m.Lock()
+	defer lt.m.Unlock()
+	renewed := 0
+	for _, l := range lt.leases {
+		if l.Owner == owner && !l.Expiry.Before(now) {
+			l.Expiry = now.Add(l.TTL)
+			renewed++
+		}
+	}
+	if renewed == 0 {
+		return errors.Wrapf(ErrNotFound, "leases of %s", owner)
+	}
+	return nil
+}
+
+// extendLease sets the ttl of the lease of ip address ip and extends the
+// lease by the ttl from now, also when it expired and was not reaped yet
+func (ipam *IpTree[T]) extendLease(ip string, ttl time.Duration) error {
+	if ipam.leases == nil {
+		return errors.New("tree does not support leases")
+	}
+	lt := ipam.leases
+	lt.m.Lock()
+	defer lt.m.Unlock()
+	l, ok := lt.leases[ip]
+	if !ok {
+		return errors.Wrapf(ErrNotFound, "lease of %s", ip)
+	}
+	l.TTL = ttl
+	l.Expiry = ipam.now().Add(ttl)
+	return nil
+}
+
+// Leases returns the leases of the tree sorted by ip address
+func (ipam *IpTree[T]) Leases() []Lease {
+	if ipam.leases == nil {
+		return nil
+	}
+	lt := ipam.leases
+	lt.m.Lock()
+	defer lt.m.Unlock()
+	leases := make([]Lease, 0, len(lt.leases))
+	for _, l := range lt.leases {
+		leases = append(leases, *l)
+	}
+	sort.Slice(leases, func(i, j int) bool {
+		a, _ := netaddr.ParseIP(leases[i].IP)
+		b, _ := netaddr.ParseIP(leases[j].IP)
+		return a.Less(b)
+	})
+	return leases
+}
+
+// ReapExpired releases the ip addresses whose lease expired, the releases
+// are emitted as expired events. It returns the released ip addresses, on
+// an error the addresses released before the failure
+func (ipam *IpTree[T]) ReapExpired() (_ []string, err error) {
+	if ipam.leases == nil {
+		return nil, nil
+	}
+	defer ipam.metrics.observe(opReap, &err)
+	now := ipam.now()
+	expired := []string{}
+	for _, l := range ipam.Leases() {
+		if l.Expiry.Before(now) {
+			expired = append(expired, l.IP)
+		}
+	}
+	for i, ip := range expired {
+		if err := ipam.releaseAddress(ip, EventExpired); err != nil {
+			return expired[:i], errors.Wrapf(err, "cannot release expired ip address %s", ip)
+		}
+	}
+	return expired, nil
+}
+
+// StartReaper releases expired leases every interval until the context is
+// done, l guards the tree when it is shared with other goroutines and can
+// be nil. A failed reap is counted as a reap operation in the metrics of
+// the tree and passed to onError when it is set, the reaper keeps running
+func (ipam *IpTree[T]) StartReaper(ctx context.Context, interval time.Duration, l sync.Locker, onError func(error)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if l != nil {
+					l.Lock()
+				}
+				_, err := ipam.ReapExpired()
+				if l != nil {
+					l.Unlock()
+				}
+				if err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestReapExpired(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	ipam := New[string](WithClock(clock))
+	if err := ipam.AddPrefix("10.0.0.0/24", "net"); err != nil {
+		t.Fatal(err)
+	}
+	for i, ttl := range []time.Duration{time.Minute, time.Hour} {
+		if _, err := ipam.AllocateIPWithLease("10.0.0.0/24", "host", "owner", ttl); err != nil {
+			t.Fatalf("lease %d: %v", i, err)
+		}
+	}
+	clock.Advance(2 * time.Minute)
+	expired, err := ipam.ReapExpired()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expired) != 1 || expired[0] != "10.0.0.1" {
+		t.Errorf("reaped %v, want [10.0.0.1]", expired)
+	}
+	if _, ok := ipam.GetIP("10.0.0.1"); ok {
+		t.Error("expired address is still allocated")
+	}
+	if n := len(ipam.Leases()); n != 1 {
+		t.Errorf("%d leases left, want 1", n)
+	}
+}
+
+// a reap that fails is reported to the callback and counted in the metrics
+func TestReaperReportsErrors(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	ipam := New[string](WithClock(clock))
+	if err := ipam.AddPrefix("10.0.0.0/24", "net"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ipam.AllocateIPWithLease("10.0.0.0/24", "host", "owner", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	ipam.SetAuditSink(&failingSink{fail: true})
+	clock.Advance(2 * time.Minute)
+
+	errs := make(chan error, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var m sync.Mutex
+	ipam.StartReaper(ctx, time.Millisecond, &m, func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	})
+	select {
+	case err := <-errs:
+		if errors.Cause(err) != errSinkFailed {
+			t.Errorf("reaper reported %v, want %v", err, errSinkFailed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reaper did not report the error")
+	}
+	cancel()
+	m.Lock()
+	defer m.Unlock()
+	if n := testutil.ToFloat64(ipam.metrics.operations.WithLabelValues(opReap, "internal")); n < 1 {
+		t.Errorf("%v failed reaps counted", n)
+	}
+	if _, ok := ipam.GetIP("10.0.0.1"); !ok {
+		t.Error("address of the failed reap was released")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"inet.af/netaddr"
+)
+
+// LookupResult is the outcome of a longest prefix match of an ip address
+type LookupResult[T any] struct {
+	IP string
+	// Chain holds the prefixes, range and allocated address that cover the
+	// ip address ordered from the least to the most specific
+	Chain []Entry[T]
+}
+
+// Match returns the most specific entry of the lookup
+func (r *LookupResult[T]) Match() Entry[T] {
+	return r.Chain[len(r.Chain)-1]
+}
+
+// Lookup returns the most specific entry of the tree that holds the ip
+// address together with all prefixes and ranges that cover it, ErrNotFound
+// is returned when no entry covers the address
+func (ipam *IpTree[T]) Lookup(ip string) (*LookupResult[T], error) {
+	ip, t, err := ipam.tree(ip)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := netaddr.ParseIP(ip)
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalid, "cannot parse ip address %s: %v", ip, err)
+	}
+	r := ipam.lookup(addr, t)
+	if r == nil {
+		return nil, errors.Wrapf(ErrNotFound, "no prefix holds ip address %s", ip)
+	}
+	return r, nil
+}
+
+// LookupMany looks up a batch of ip addresses, the result of an address is
+// at the same index as the address and is nil when the address is invalid
+// or no entry covers it
+func (ipam *IpTree[T]) LookupMany(ips []string) []*LookupResult[T] {
+	results := make([]*LookupResult[T], len(ips))
+	for i, s := range ips {
+		addr, err := netaddr.ParseIP(s)
+		if err != nil {
+			continue
+		}
+		addr = addr.Unmap()
+		results[i] = ipam.lookup(addr, ipam.GetTree(familyOfIP(addr)))
+	}
+	return results
+}
+
+// lookup returns the chain of entries in tree t that cover the canonical ip
+// address addr, nil when there is none
+func (ipam *IpTree[T]) lookup(addr netaddr.IP, t Backend) *LookupResult[T] {
+	chain := []lookupEntry[T]{}
+	host := netaddr.IPPrefixFrom(addr, addr.BitLen())
+	t.WalkMatch(host, func(_ netaddr.IPPrefix, v interface{}) bool {
+		d, ok := ipam.getData(v)
+		if !ok {
+			return true
+		}
+		for k, value := range d.GetValue() {
+			// the keys of a node that covers the address are expected to
+			// cover it as well, the check guards against a stale node
+			r, err := keyRange(k)
+			if err != nil || !r.Contains(addr) {
+				continue
+			}
+			chain = append(chain, lookupEntry[T]{
+				Entry: Entry[T]{Key: k, Value: value},
+				r:     r,
+				kind:  lookupKind(k),
+			})
+		}
+		return true
+	})
+	ipam.rangesOverlapping(netaddr.IPRangeFrom(addr, addr), func(n *rangeNode[T]) bool {
+		chain = append(chain, lookupEntry[T]{
+			Entry: Entry[T]{Key: n.key, Value: n.value},
+			r:     n.r,
+			kind:  lookupKind(n.key),
+		})
+		return true
+	})
+	if len(chain) == 0 {
+		return nil
+	}
+	// every entry holds the address, so an entry that starts before another
+	// or starts at the same address and ends after it contains the other
+	sort.SliceStable(chain, func(i, j int) bool {
+		a, b := chain[i].r, chain[j].r
+		switch {
+		case a.From() != b.From():
+			return a.From().Less(b.From())
+		case a.To() != b.To():
+			return b.To().Less(a.To())
+		}
+		return chain[i].kind < chain[j].kind
+	})
+	r := &LookupResult[T]{
+		IP:    addr.String(),
+		Chain: make([]Entry[T], 0, len(chain)),
+	}
+	for _, e := range chain {
+		e.Revision, _ = ipam.EntryRevision(e.Key)
+		r.Chain = append(r.Chain, e.Entry)
+	}
+	return r
+}
+
+// lookupEntry is an entry of a lookup chain with the ordering keys, the
+// entries are ordered by the addresses they span and for the same span a
+// prefix is less specific than a range and a range less specific than an
+// address
+type lookupEntry[T any] struct {
+	Entry[T]
+	r    netaddr.IPRange
+	kind int
+}
+
+func lookupKind(k string) int {
+	switch {
+	case strings.Contains(k, "/"):
+		return 0
+	case strings.Contains(k, "-"):
+		return 1
+	}
+	return 2
+}
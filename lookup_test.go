@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// the chain is ordered by containment, a prefix inside a range comes after
+// the range and a nested range after the range that holds it
+func TestLookupChainOrder(t *testing.T) {
+	ipam := New[string](WithPolicy(Policy{AllowNestedRanges: true, AllowPrefixInRange: true}))
+	for _, p := range []string{"10.0.0.0/24", "10.0.0.64/26"} {
+		if err := ipam.AddPrefix(p, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, ra := range []string{"10.0.0.0-10.0.0.127", "10.0.0.70-10.0.0.80"} {
+		if err := ipam.AddRange(ra, ra); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ipam.ClaimIP("10.0.0.72", "host"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip   string
+		want []string
+	}{
+		{"10.0.0.72", []string{"10.0.0.0/24", "10.0.0.0-10.0.0.127", "10.0.0.64/26", "10.0.0.70-10.0.0.80", "10.0.0.72"}},
+		{"10.0.0.65", []string{"10.0.0.0/24", "10.0.0.0-10.0.0.127", "10.0.0.64/26"}},
+		{"10.0.0.200", []string{"10.0.0.0/24"}},
+	}
+	for _, tt := range tests {
+		r, err := ipam.Lookup(tt.ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys := []string{}
+		for _, e := range r.Chain {
+			keys = append(keys, e.Key)
+		}
+		if !reflect.DeepEqual(keys, tt.want) {
+			t.Errorf("Lookup(%s) = %v, want %v", tt.ip, keys, tt.want)
+		}
+	}
+}
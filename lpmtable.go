@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/binary"
+	"sort"
+	"sync/atomic"
+
+	"inet.af/netaddr"
+)
+
+// LPMTable is a compiled read-only longest prefix match table of a tree.
+// The prefixes, ranges and ip addresses of the tree are flattened in
+// disjoint segments of addresses such that a lookup is a binary search on
+// integers that does not allocate. A table does not change when the tree
+// changes, it is compiled again and swapped in with LPM
+type LPMTable[T any] struct {
+	// Revision is the revision of the tree the table was compiled from
+	Revision uint64
+	v4       lpmSegments[uint32]
+	v6       lpmSegments[uint128]
+	entries  []Entry[T]
+}
+
+// uint128 is an ipv6 address as an integer
+type uint128 struct {
+	hi, lo uint64
+}
+
+func (u uint128) less(v uint128) bool {
+	return u.hi < v.hi || u.hi == v.hi && u.lo < v.lo
+}
+
+// lpmSegments are the segments of an address family, segment i starts at
+// starts[i] and ends before starts[i+1], entries[i] is the index of the
+// most specific entry of the segment or -1 when no entry covers it
+type lpmSegments[K uint32 | uint128] struct {
+	starts  []K
+	entries []int32
+}
+
+// lookup returns the entry index of the segment that holds key k, less is
+// passed in such that both key types share the search
+func (s *lpmSegments[K]) lookup(k K, less func(a, b K) bool) int32 {
+	// binary search for the last segment that starts at or before k
+	lo, hi := 0, len(s.starts)
+	for lo < hi {
+		m := int(uint(lo+hi) >> 1)
+		if less(k, s.starts[m]) {
+			hi = m
+		} else {
+			lo = m + 1
+		}
+	}
+	if lo == 0 {
+		return -1
+	}
+	return s.entries[lo-1]
+}
+
+func lessUint32(a, b uint32) bool   { return a < b }
+func lessUint128(a, b uint128) bool { return a.less(b) }
+
+func ipv4Key(ip netaddr.IP) uint32 {
+	a := ip.As4()
+	return binary.BigEndian.Uint32(a[:])
+}
+
+func ipv6Key(ip netaddr.IP) uint128 {
+	a := ip.As16()
+	return uint128{hi: binary.BigEndian.Uint64(a[:8]), lo: binary.BigEndian.Uint64(a[8:])}
+}
+
+// Lookup returns the most specific entry that holds the ip address
+func (t *LPMTable[T]) Lookup(ip netaddr.IP) (Entry[T], bool) {
+	ip = ip.Unmap()
+	var i int32
+	switch {
+	case ip.Is4():
+		i = t.v4.lookup(ipv4Key(ip), lessUint32)
+	case ip.Is6():
+		i = t.v6.lookup(ipv6Key(ip), lessUint128)
+	default:
+		i = -1
+	}
+	if i < 0 {
+		return Entry[T]{}, false
+	}
+	return t.entries[i], true
+}
+
+// Len returns the amount of segments of the table
+func (t *LPMTable[T]) Len() int {
+	return len(t.v4.starts) + len(t.v6.starts)
+}
+
+// Compile returns the LPM table of the current content of the tree
+func (ipam *IpTree[T]) Compile() *LPMTable[T] {
+	t := &LPMTable[T]{
+		Revision: ipam.Revision(),
+		entries:  []Entry[T]{},
+	}
+	for _, f := range Families {
+		intervals := []lpmInterval{}
+		for _, e := range ipam.ExportFamily(f) {
+			r, err := keyRange(e.Key)
+			if err != nil {
+				continue
+			}
+			intervals = append(intervals, lpmInterval{r: r, kind: lookupKind(e.Key), entry: int32(len(t.entries))})
+			t.entries = append(t.entries, e)
+		}
+		starts, entries := flatten(intervals)
+		switch f {
+		case FamilyIPv4:
+			t.v4.entries = entries
+			for _, ip := range starts {
+				t.v4.starts = append(t.v4.starts, ipv4Key(ip))
+			}
+		case FamilyIPv6:
+			t.v6.entries = entries
+			for _, ip := range starts {
+				t.v6.starts = append(t.v6.starts, ipv6Key(ip))
+			}
+		}
+	}
+	return t
+}
+
+// lpmInterval is the range of addresses of an entry of the table
+type lpmInterval struct {
+	r     netaddr.IPRange
+	kind  int
+	entry int32
+}
+
+// flatten returns the segments of nested intervals, every segment holds
+// the most specific interval that covers it. The intervals of a tree nest
+// as prefixes hold ranges and ip addresses and ranges do not overlap
+func flatten(intervals []lpmInterval) ([]netaddr.IP, []int32) {
+	// outer intervals go before the intervals they hold, for an equal
+	// range a prefix goes before a range and a range before an address
+	sort.SliceStable(intervals, func(i, j int) bool {
+		a, b := intervals[i].r, intervals[j].r
+		if a.From() != b.From() {
+			return a.From().Less(b.From())
+		}
+		if a.To() != b.To() {
+			return b.To().Less(a.To())
+		}
+		return intervals[i].kind < intervals[j].kind
+	})
+
+	starts := []netaddr.IP{}
+	entries := []int32{}
+	emit := func(at netaddr.IP, entry int32) {
+		n := len(starts)
+		if n > 0 && starts[n-1] == at {
+			entries[n-1] = entry
+			if n > 1 && entries[n-2] == entry {
+				starts, entries = starts[:n-1], entries[:n-1]
+			}
+			return
+		}
+		if n > 0 && entries[n-1] == entry || n == 0 && entry < 0 {
+			return
+		}
+		starts = append(starts, at)
+		entries = append(entries, entry)
+	}
+
+	stack := []lpmInterval{}
+	// pop closes the innermost open interval, its remaining addresses go
+	// to the interval that holds it
+	pop := func() {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		entry := int32(-1)
+		if len(stack) > 0 {
+			entry = stack[len(stack)-1].entry
+		}
+		if next := top.r.To().Next(); !next.IsZero() {
+			emit(next, entry)
+		}
+	}
+	for _, iv := range intervals {
+		for len(stack) > 0 && stack[len(stack)-1].r.To().Less(iv.r.From()) {
+			pop()
+		}
+		stack = append(stack, iv)
+		emit(iv.r.From(), iv.entry)
+	}
+	for len(stack) > 0 {
+		pop()
+	}
+	return starts, entries
+}
+
+// LPM holds the LPM table that serves lookups, a new table is swapped in
+// atomically while lookups continue on the previous one
+type LPM[T any] struct {
+	table atomic.Pointer[LPMTable[T]]
+}
+
+// NewLPM returns an LPM that serves lookups from table t
+func NewLPM[T any](t *LPMTable[T]) *LPM[T] {
+	l := &LPM[T]{}
+	l.table.Store(t)
+	return l
+}
+
+// Table returns the table that serves the lookups
+func (l *LPM[T]) Table() *LPMTable[T] {
+	return l.table.Load()
+}
+
+// Swap replaces the table that serves the lookups and returns the previous
+// one
+func (l *LPM[T]) Swap(t *LPMTable[T]) *LPMTable[T] {
+	return l.table.Swap(t)
+}
+
+// Lookup returns the most specific entry that holds the ip address in the
+// current table
+func (l *LPM[T]) Lookup(ip netaddr.IP) (Entry[T], bool) {
+	t := l.table.Load()
+	if t == nil {
+		return Entry[T]{}, false
+	}
+	return t.Lookup(ip)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"inet.af/netaddr"
+)
+
+// benchTree returns a tree with 10.0.0.0/8 and n /24 prefixes below it
+func benchTree(tb testing.TB, n int, opts ...Option) *IpTree[string] {
+	tb.Helper()
+	ipam := New[string](opts...)
+	if err := ipam.AddPrefix("10.0.0.0/8", "root"); err != nil {
+		tb.Fatal(err)
+	}
+	for i := 0; i < n && i < 1<<16; i++ {
+		p := fmt.Sprintf("10.%d.%d.0/24", i>>8, i&0xff)
+		if err := ipam.AddPrefix(p, p); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return ipam
+}
+
+// benchAddresses returns the addresses looked up by the benchmarks, they
+// are spread over the /24 prefixes of benchTree
+func benchAddresses(n int) []netaddr.IP {
+	ips := make([]netaddr.IP, 1024)
+	for i := range ips {
+		j := i * 7919 % max(n, 1)
+		ips[i] = netaddr.IPv4(10, byte(j>>8), byte(j&0xff), byte(i))
+	}
+	return ips
+}
+
+// benchSizes are the amounts of /24 prefixes of the lookup benchmarks
+var benchSizes = []int{1000, 1 << 16}
+
+func BenchmarkLPMTableLookup(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			table := benchTree(b, n).Compile()
+			ips := benchAddresses(n)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				table.Lookup(ips[i%len(ips)])
+			}
+		})
+	}
+}
+
+func BenchmarkMatchIP(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			t := benchTree(b, n).GetTree(FamilyIPv4).(*critbitBackend).n
+			ips := benchAddresses(n)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				t.MatchIP(ips[i%len(ips)].IPAddr().IP)
+			}
+		})
+	}
+}
+
+func BenchmarkRadixMatch(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			t := benchTree(b, n, WithBackend(NewRadixBackend)).GetTree(FamilyIPv4)
+			ips := benchAddresses(n)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				t.Match(ips[i%len(ips)])
+			}
+		})
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	type interval struct {
+		r     string
+		kind  int
+		entry int32
+	}
+	tests := []struct {
+		name      string
+		intervals []interval
+		starts    []string
+		entries   []int32
+	}{
+		{
+			name:      "single",
+			intervals: []interval{{"10.0.0.0-10.0.0.255", 0, 0}},
+			starts:    []string{"10.0.0.0", "10.0.1.0"},
+			entries:   []int32{0, -1},
+		},
+		{
+			name: "nested",
+			intervals: []interval{
+				{"10.0.0.15-10.0.0.15", 2, 2},
+				{"10.0.0.0-10.0.0.255", 0, 0},
+				{"10.0.0.10-10.0.0.20", 1, 1},
+			},
+			starts:  []string{"10.0.0.0", "10.0.0.10", "10.0.0.15", "10.0.0.16", "10.0.0.21", "10.0.1.0"},
+			entries: []int32{0, 1, 2, 1, 0, -1},
+		},
+		{
+			name: "adjacent",
+			intervals: []interval{
+				{"10.0.0.10-10.0.0.19", 1, 1},
+				{"10.0.0.0-10.0.0.9", 1, 0},
+			},
+			starts:  []string{"10.0.0.0", "10.0.0.10", "10.0.0.20"},
+			entries: []int32{0, 1, -1},
+		},
+		{
+			name: "adjacent inside a prefix",
+			intervals: []interval{
+				{"10.0.0.0-10.0.0.255", 0, 0},
+				{"10.0.0.0-10.0.0.9", 1, 1},
+				{"10.0.0.10-10.0.0.19", 1, 2},
+				{"10.0.0.250-10.0.0.255", 1, 3},
+			},
+			starts:  []string{"10.0.0.0", "10.0.0.10", "10.0.0.20", "10.0.0.250", "10.0.1.0"},
+			entries: []int32{1, 2, 0, 3, -1},
+		},
+		{
+			name: "range equal to a prefix",
+			intervals: []interval{
+				{"10.0.0.0-10.0.0.255", 1, 1},
+				{"10.0.0.0-10.0.0.255", 0, 0},
+			},
+			starts:  []string{"10.0.0.0", "10.0.1.0"},
+			entries: []int32{1, -1},
+		},
+		{
+			name:      "end of the address space",
+			intervals: []interval{{"255.255.255.0-255.255.255.255", 0, 0}},
+			starts:    []string{"255.255.255.0"},
+			entries:   []int32{0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			intervals := []lpmInterval{}
+			for _, iv := range tt.intervals {
+				intervals = append(intervals, lpmInterval{r: mustRange(t, iv.r), kind: iv.kind, entry: iv.entry})
+			}
+			starts, entries := flatten(intervals)
+			got := []string{}
+			for _, ip := range starts {
+				got = append(got, ip.String())
+			}
+			if !reflect.DeepEqual(got, tt.starts) || !reflect.DeepEqual(entries, tt.entries) {
+				t.Errorf("flatten = %v %v, want %v %v", got, entries, tt.starts, tt.entries)
+			}
+		})
+	}
+}
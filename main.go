@@ -1,470 +1,464 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"math"
 	"net"
+	"sort"
 	"strings"
 
-	"github.com/k-sone/critbitgo"
 	"github.com/pkg/errors"
 	"inet.af/netaddr"
+	ctrl "sigs.k8s.io/controller-runtime"
 )
 
-func callback(r *net.IPNet, v interface{}) bool {
-	if c, ok := v.(Data); ok {
-		fmt.Println("  Prefix", r, "Value", c.GetValue(), "ipPrefix", c.GetMeta().HasIpPrefix(), "ipRange", c.GetMeta().HasIpRange())
-	} else {
-		fmt.Println("  Prefix", r, "Value", v)
-	}
+func callback[T any](p netaddr.IPPrefix, d *Data[T]) bool {
+	fmt.Println("  Prefix", p, "Value", d.GetValue(), "ipPrefix", d.GetMeta().HasIpPrefix(), "ipRange", d.GetMeta().HasIpRange())
 	return true
 }
 
-type IpTree struct {
-	t *critbitgo.Net
+// IpTree is an ipam tree holding values of type T for the prefixes, ranges
+// and ip addresses stored in it
+type IpTree[T any] struct {
+	// every address family has its own tree such that ipv4 and ipv6
+	// pools never interfere
+	t map[Family]Backend
+	// ranges are not stored in the nodes of the tree but once in a range
+	// index of their address family
+	ranges map[Family]*rangeIndex[T]
+	opts   options
+	// events is nil for a dummy tree, changes of a dummy tree are not
+	// watched
+	events *eventLog[T]
+	// metrics of the tree, nil when the tree is not instrumented such as a
+	// dummy tree, the pre-checks are not observed
+	metrics *metrics
+	// leases of the allocated ip addresses, nil for a dummy tree
+	leases *leaseTable
+	// actor and reason are recorded in the audit log for the changes made
+	// through a view of the tree, see As
+	actor  string
+	reason string
 }
 
-func New() *IpTree {
-	return &IpTree{
-		t: critbitgo.NewNet(),
-	}
+func New[T any](opts ...Option) *IpTree[T] {
+	ipam := &IpTree[T]{}
+	for _, o := range opts {
+		o(&ipam.opts)
+	}
+	ipam.t = newBackends(ipam.opts)
+	ipam.ranges = newRangeIndexes[T]()
+	ipam.events = newEventLog[T](ipam.opts.eventHistory)
+	ipam.metrics = newMetrics()
+	ipam.leases = newLeaseTable()
+	return ipam
 }
 
-func (ipam *IpTree) GetTree() *critbitgo.Net {
-	return ipam.t
+// GetTree returns the tree of address family f
+func (ipam *IpTree[T]) GetTree(f Family) Backend {
+	return ipam.t[f]
 }
 
-func (ipam *IpTree) validateOverlap(s string) (bool, *net.IPNet, *Data, error) {
-	var start, end netaddr.IP
-	if strings.Contains(s, "-") {
-		// range
-		ra, err := netaddr.ParseIPRange(s)
-		if err != nil {
-			return false, nil, nil, errors.New("validation failed")
-		}
-		start, end, _, err = netValidateIpRange(&ra)
-		if err != nil {
-			return false, nil, nil, errors.New("validation failed")
-		}
-	} else {
-		// prefix
-		p, err := netaddr.ParseIPPrefix(s)
-		if err != nil {
-			return false, nil, nil, errors.New("validation failed")
-		}
-		start, end, _, err = netValidateIpNet(&p)
-		if err != nil {
-			return false, nil, nil, errors.New("validation failed")
-		}
-	}
-	t := ipam.GetTree()
-	keyStart, v, err := t.MatchIP(start.IPAddr().IP)
+// tree returns the canonical form of the prefix, range or ip address s
+// together with the tree of its address family
+func (ipam *IpTree[T]) tree(s string) (string, Backend, error) {
+	s, f, err := Canonicalize(s)
 	if err != nil {
-		return false, nil, nil, err
-	}
-	if c, ok := v.(Data); ok {
-		fmt.Println("  ", start.IPAddr().IP, "Start", keyStart, "Value", c.GetValue(), "ipPrefix", c.GetMeta().HasIpPrefix(), "ipRange", c.GetMeta().HasIpRange())
-	} else {
-		fmt.Println("  ", start.IPAddr().IP, "Start", keyStart, "Value", v)
+		return "", nil, err
 	}
+	return s, ipam.GetTree(f), nil
+}
 
-	keyEnd, v, err := t.MatchIP(end.IPAddr().IP)
-	if err != nil {
-		return false, nil, nil, err
+// Clear removes all entries of all address families
+func (ipam *IpTree[T]) Clear() {
+	for _, f := range Families {
+		ipam.GetTree(f).Clear()
+		ipam.rangeIndex(f).clear()
 	}
-	if c, ok := v.(Data); ok {
-		fmt.Println("  ", end.IPAddr().IP, "End", keyEnd, "Value", c.GetValue(), "ipPrefix", c.GetMeta().HasIpPrefix(), "ipRange", c.GetMeta().HasIpRange())
-	} else {
-		fmt.Println("  ", end.IPAddr().IP, "End", keyEnd, "Value", v)
+}
+
+// Size returns the amount of nodes in the trees of all address families
+func (ipam *IpTree[T]) Size() int {
+	size := 0
+	for _, f := range Families {
+		size += ipam.GetTree(f).Size()
 	}
+	return size
+}
 
-	if keyStart == nil || keyEnd == nil {
-		// not found
-		fmt.Println("Overlap check passed, no result found")
-		return false, nil, nil, nil
+// getData returns the typed data stored in a tree node, the nodes hold a
+// pointer to their data such that reading it does not copy
+func (ipam *IpTree[T]) getData(v interface{}) (*Data[T], bool) {
+	d, ok := v.(*Data[T])
+	return d, ok
+}
+
+// validateOverlap matches the first and the last address of the canonical
+// range r in the tree, the range overlaps when they match different nodes
+func (ipam *IpTree[T]) validateOverlap(r netaddr.IPRange) (bool, netaddr.IPPrefix, *Data[T], error) {
+	start, end := r.From(), r.To()
+	t := ipam.GetTree(familyOfIP(start))
+	keyStart, _, foundStart := t.Match(start)
+	keyEnd, v, foundEnd := t.Match(end)
+	if !foundStart || !foundEnd {
+		return false, netaddr.IPPrefix{}, nil, nil
 	}
-	if keyStart.String() == keyEnd.String() {
-		fmt.Println("Overlap check passed, key match")
-		if c, ok := v.(Data); ok {
-			return false, keyStart, &c, nil
+	if keyStart == keyEnd {
+		if c, ok := ipam.getData(v); ok {
+			return false, keyStart, c, nil
 		}
-
 	}
-	fmt.Println("Overlap check failed, key mismatch")
-	return true, nil, nil, nil
+	return true, netaddr.IPPrefix{}, nil, nil
 }
 
 // PreCheckAddition validates if the addition of a prefix/range results in
-// a valid tree or not
-func (ipam *IpTree) PreCheckAddition(s string) (bool, error) {
-	fmt.Println("Pre Check adding prefix or range ...")
-	// clear the ipam
-	t := ipam.GetTree()
-	t.Clear()
-
+// a valid tree or not, the validation is performed on a dummy tree that
+// holds the related entries of this tree
+func (ipam *IpTree[T]) PreCheckAddition(s string) (bool, error) {
+	s, _, err := Canonicalize(s)
+	if err != nil {
+		return false, err
+	}
 	prefixes, err := GetPrefixes(s)
 	if err != nil {
 		return false, err
 	}
 
-	pfxs := NewIPPrefixes()
-	for _, p := range prefixes {
-		// get the parent
-		parent := ipam.Parent(p)
-		pfxs.AddPrefixes(parent)
-		if len(parent) > 0 {
-			// get the children based on the parent of the new prefix
-			pfxs.AddPrefixes(ipam.Children(parent[0]))
+	keys := ipam.relatedEntries(prefixes)
+	for _, k := range keys {
+		if k == s && strings.Contains(s, "-") {
+			return false, errors.Wrapf(ErrOverlap, "range %s exists", s)
 		}
-		// add the new prefix to the list
-		pfxs.AddPrefixes([]netaddr.IPPrefix{p})
 	}
+	// add the new prefix/range to the list
+	keys = append(keys, s)
 
-	fmt.Println(pfxs.GetPrefixes())
-
-	return ipam.PreCheck(pfxs.GetPrefixes())
+	dummy := ipam.newDummy()
+	return dummy.preCheckEntries(keys, "")
 }
 
 // PreCheckDeletion validates if the deletion of a prefix/range results in
-// a valid tree or not
-func (ipam *IpTree) PreCheckDeletion(s string) (bool, error) {
-	fmt.Println("Pre Check delting prefix or range ...")
-	// clear the ipam
-	t := ipam.GetTree()
-	t.Clear()
-
+// a valid tree or not, the validation is performed on a dummy tree that
+// holds the related entries of this tree without the deleted entry.
+// A prefix is validated for a strict deletion
+func (ipam *IpTree[T]) PreCheckDeletion(s string) (bool, error) {
+	s, _, err := Canonicalize(s)
+	if err != nil {
+		return false, err
+	}
 	prefixes, err := GetPrefixes(s)
 	if err != nil {
 		return false, err
 	}
+	if !strings.Contains(s, "-") {
+		if children := ipam.subtree(prefixes[0]); len(children) > 0 {
+			return false, errors.Wrapf(ErrHasChildren, "prefix %s has %d children", s, len(children))
+		}
+	}
+
+	dummy := ipam.newDummy()
+	return dummy.preCheckEntries(ipam.relatedEntries(prefixes), s)
+}
+
+// newDummy returns an empty tree with the same options as this tree
+func (ipam *IpTree[T]) newDummy() *IpTree[T] {
+	return &IpTree[T]{
+		t:      newBackends(ipam.opts),
+		ranges: newRangeIndexes[T](),
+		opts:   ipam.opts,
+	}
+}
 
+// relatedEntries returns the keys of the entries that are stored in the
+// parents of the prefixes and in the children of their top level parent,
+// together with the ranges that overlap the top level parent
+func (ipam *IpTree[T]) relatedEntries(prefixes []netaddr.IPPrefix) []string {
+	keys := []string{}
+	seen := map[string]struct{}{}
 	pfxs := NewIPPrefixes()
 	for _, p := range prefixes {
-		pfxs.AddPrefixes(ipam.Parents(p))
-		pfxs.AddPrefixes(ipam.Children(p))
+		parents := ipam.Parents(p)
+		pfxs.AddPrefixes(parents)
+		scope := p
+		if len(parents) > 0 {
+			// get the children based on the parent of the new prefix
+			scope = parents[0]
+		}
+		pfxs.AddPrefixes(ipam.Children(scope))
+		pfxs.AddPrefixes([]netaddr.IPPrefix{p})
+		ipam.rangesOverlapping(scope.Range(), func(n *rangeNode[T]) bool {
+			if _, ok := seen[n.key]; !ok {
+				seen[n.key] = struct{}{}
+				keys = append(keys, n.key)
+			}
+			return true
+		})
 	}
 
-	return ipam.PreCheck(pfxs.GetPrefixes())
+	for _, p := range pfxs.GetPrefixes() {
+		v, ok := ipam.GetTree(familyOfIP(p.IP())).Get(p)
+		if !ok {
+			continue
+		}
+		if d, ok := ipam.getData(v); ok {
+			for k := range d.GetValue() {
+				if _, ok := seen[k]; !ok {
+					seen[k] = struct{}{}
+					keys = append(keys, k)
+				}
+			}
+		}
+	}
+	return keys
+}
+
+// preCheckEntries adds the prefixes and ranges with keys to the dummy tree,
+// the prefixes are added in order before the ranges, the entry with key
+// skip is not added
+func (ipam *IpTree[T]) preCheckEntries(keys []string, skip string) (bool, error) {
+	prefixes := []netaddr.IPPrefix{}
+	ranges := []string{}
+	for _, k := range keys {
+		if k == skip {
+			continue
+		}
+		if strings.Contains(k, "-") {
+			ranges = append(ranges, k)
+			continue
+		}
+		if !strings.Contains(k, "/") {
+			// allocated ip addresses do not constrain prefixes and ranges
+			continue
+		}
+		p, err := netaddr.ParseIPPrefix(k)
+		if err != nil {
+			return false, errors.Wrap(err, "cannot parse prefix")
+		}
+		prefixes = append(prefixes, p)
+	}
+	if ok, err := ipam.PreCheck(prefixes); !ok {
+		return false, err
+	}
+	sort.Strings(ranges)
+	for _, ra := range ranges {
+		var dummy T
+		if ok, err := ipam.PreCheckAddRange(ra, dummy); !ok {
+			return false, err
+		}
+	}
+	return true, nil
 }
 
 // Precheck validates if the insertion in the tree
-func (ipam *IpTree) PreCheck(p []netaddr.IPPrefix) (bool, error) {
-	fmt.Println("Check....")
+func (ipam *IpTree[T]) PreCheck(p []netaddr.IPPrefix) (bool, error) {
 	// sort the data such that we validate in order
 	sortedPrefixes := SortPrefixes(p)
 	// validate the result
 	for _, p := range sortedPrefixes {
-		fmt.Println("######### ", p, " ##########")
-
-		success, err := ipam.PreCheckAddPrefix(p.String(), "dummy")
-		if err != nil {
-			return false, err
-		}
+		var dummy T
+		success, err := ipam.PreCheckAddPrefix(p.String(), dummy)
 		if !success {
-			return false, nil
+			return false, err
 		}
 	}
 	return true, nil
 }
 
 // PreCheckAddPrefix adds a prefix to the dummy tree, to validate if the insertion would be successfull
-func (ipam *IpTree) PreCheckAddPrefix(p string, value interface{}) (bool, error) {
-	fmt.Println("PreCheckAddPrefix...")
-	t := ipam.GetTree()
-	overlap, _, d, err := ipam.validateOverlap(p)
+// a rejection by the policy is returned as a conflict error
+func (ipam *IpTree[T]) PreCheckAddPrefix(p string, value T) (bool, error) {
+	pfx, err := ParsePrefix(p)
+	if err != nil {
+		return false, err
+	}
+	overlap, _, _, err := ipam.validateOverlap(pfx.Range())
 	if err != nil {
 		return false, errors.Wrap(err, "error validating overlap")
 	}
 	if overlap {
-		// not successfull
-		fmt.Println("PreCheckAddPrefix -> Overlap detetcted")
-		return false, nil
-	} else {
-		fmt.Println("No Overlap")
-		// successfull
-		if d == nil || !d.GetMeta().HasIpRange() {
-			// only add when the parent prefix is not a range
-			v := Data{
-				meta: &Metadata{
-					ipPrefix: true,
-				},
-				value: map[string]interface{}{p: value},
-			}
-			if err := t.AddCIDR(p, v); err != nil {
-				fmt.Println("error adding prefix")
-				return false, nil
-			}
-			// success
-			return true, nil
-		} else {
-			// inserting data in a object that has a range is not allowed
-			return false, nil
-		}
+		return false, errors.Wrapf(ErrOverlap, "prefix %s", pfx)
 	}
-}
-
-// AddPrefix adds a prefix to the tree in a very open minded way
-// overlap is not validated as this method, the method assumes validation
-// was performed before calling it
-func (ipam *IpTree) AddPrefix(p string, value interface{}) error {
-	fmt.Println("AddPrefix...")
-	// use the ipamtree
-	t := ipam.GetTree()
-	// execute this function to get the data since overlap validation occured already
-	_, key, d, err := ipam.validateOverlap(p)
-	if err != nil {
-		return errors.Wrap(err, "error validating overlap")
+	if err := ipam.checkAddPrefix(pfx); err != nil {
+		return false, err
 	}
-	if d != nil && key.String() == p {
-		d.GetMeta().SetIpPrefix()
-		d.AddValue(p, value)
-		if err := t.AddCIDR(p, *d); err != nil {
-			return errors.Wrap(err, "error adding prefix")
-		}
-	} else {
-		v := Data{
-			meta: &Metadata{
-				ipPrefix: true,
-			},
-			value: map[string]interface{}{p: value},
-		}
-		if err := t.AddCIDR(p, v); err != nil {
-			return errors.Wrap(err, "error adding prefix")
-		}
+	if err := ipam.insertPrefix(pfx, value); err != nil {
+		return false, err
 	}
-	return nil
+	// success
+	return true, nil
 }
 
-// DeletePrefix deletes a prefix to the tree in a very open minded way
-// overlap is not validated as this method, the method assumes validation
+// AddPrefix adds a prefix to the tree, the policy of the tree is enforced
+// but overlap is not validated as this method, the method assumes validation
 // was performed before calling it
-func (ipam *IpTree) DeletePrefix(p string) error {
-	// use the ipamtree
-	t := ipam.GetTree()
-	// execute this function to get the data since overlap validation occured already
-	_, key, d, err := ipam.validateOverlap(p)
+func (ipam *IpTree[T]) AddPrefix(p string, value T) error {
+	pfx, err := ParsePrefix(p)
 	if err != nil {
-		return errors.Wrap(err, "error validating overlap")
+		ipam.metrics.observe(opAddPrefix, &err)
+		return err
 	}
+	return ipam.AddIPPrefix(pfx, value)
+}
 
-	if key != nil {
-		d.DeleteValue(p)
-		if d.GetMeta().HasIpRange() {
-			d.GetMeta().ResetIpPrefix()
-			// update the data in the tree
-			if err := t.AddCIDR(p, *d); err != nil {
-				return err
-			}
-		} else {
-			if _, _, err := t.DeleteCIDR(p); err != nil {
-				return err
-			}
-		}
-
+// AddIPPrefix adds a prefix to the tree like AddPrefix
+func (ipam *IpTree[T]) AddIPPrefix(p netaddr.IPPrefix, value T) (err error) {
+	defer ipam.metrics.observe(opAddPrefix, &err)
+	if !p.IsValid() {
+		return errors.Wrapf(ErrInvalid, "invalid prefix %s", p)
 	}
-	return nil
+	p = canonicalPrefix(p)
+	if err := ipam.checkAddPrefix(p); err != nil {
+		return err
+	}
+	return ipam.insertPrefix(p, value)
 }
 
-func (ipam *IpTree) UpdatePrefix(p string) error {
-	return nil
+// insertPrefix adds the canonical prefix to the node of the prefix in the
+// tree
+func (ipam *IpTree[T]) insertPrefix(pfx netaddr.IPPrefix, value T) error {
+	p := pfx.String()
+	old, exists := ipam.getValue(p, pfx)
+	if err := ipam.addValue(pfx, p, value, (*Metadata).SetIpPrefix); err != nil {
+		return errors.Wrap(err, "error adding prefix")
+	}
+	if exists {
+		return ipam.emit(EventUpdated, p, old, value)
+	}
+	return ipam.emit(EventAdded, p, old, value)
 }
 
-func (ipam *IpTree) GetPrefix(p string) error {
-	return nil
+// DeletePrefix deletes a prefix from the tree, the entries below the prefix
+// stay in the tree and move to its parent as they always did, use
+// DeletePrefixWithOptions for a strict or a cascading deletion
+func (ipam *IpTree[T]) DeletePrefix(p string) error {
+	_, err := ipam.DeletePrefixWithOptions(p, DeleteOptions{})
+	return err
 }
 
-// PreCheckAddRange adds a range to the dummy tree, to validate if the insertion would be successfull
-func (ipam *IpTree) PreCheckAddRange(ra string, value interface{}) (bool, error) {
-	t := ipam.GetTree()
-	overlap, _, d, err := ipam.validateOverlap(ra)
+// deletePrefix deletes a prefix from the tree, the policy of the tree is
+// enforced but overlap is not validated as this method, the method assumes
+// validation was performed before calling it
+func (ipam *IpTree[T]) deletePrefix(p string) error {
+	pfx, err := ParsePrefix(p)
 	if err != nil {
-		return false, errors.Wrap(err, "error validating overlap")
+		return err
 	}
-	if overlap {
-		// not successfull
-		fmt.Println("ValidateAddRange -> Overlap detetcted")
-		return false, nil
+	if err := ipam.checkDeletePrefix(pfx); err != nil {
+		return err
 	}
-	if d != nil && !d.GetMeta().HasIpRange() {
-		// the higher level range does not overlap
-		// a range should have a parent prefix
+	p = pfx.String()
+	old, exists := ipam.getValue(p, pfx)
+	// the node of the prefix is kept when it holds range information
+	if err := ipam.deleteValue(pfx, p); err != nil {
+		return err
+	}
+	if exists {
+		var value T
+		return ipam.emit(EventDeleted, p, old, value)
+	}
+	return nil
+}
 
-		prefixes, err := getPrefixesForRange(ra)
-		if err != nil {
-			return false, errors.Wrap(err, "cannot get prefixes from range")
-		}
+// GetPrefix returns the value stored for the prefix p
+func (ipam *IpTree[T]) GetPrefix(p string) (T, bool) {
+	pfx, err := ParsePrefix(p)
+	if err != nil {
+		var value T
+		return value, false
+	}
+	return ipam.GetIPPrefix(pfx)
+}
 
-		// no overlap or errors, so we can insert the data to the tree
-		for _, p := range prefixes {
-			_, key, d, err := ipam.validateOverlap(p.String())
-			if err != nil {
-				fmt.Println("error validating overlap")
-				break
-			}
-			if key != nil && key.String() == p.String() {
-				// the range aggregate prefix matches with a parent prefix,
-				// -> augment the data
-				d.GetMeta().SetIpRange()
-				d.AddValue(ra, value)
-				if err := t.AddCIDR(p.String(), *d); err != nil {
-					return false, errors.Wrap(err, "error adding prefix")
-				}
-			} else {
-				// the range aggregate prefix does NOT match with a parent prefix
-				// -> initialize as a new prefix with the range info
-				v := Data{
-					meta: &Metadata{
-						ipRange: true,
-					},
-					value: map[string]interface{}{ra: value},
-				}
-				if err := t.AddCIDR(p.String(), v); err != nil {
-					return false, errors.Wrap(err, "error adding prefix")
-				}
-			}
+// GetIPPrefix returns the value stored for the prefix p
+func (ipam *IpTree[T]) GetIPPrefix(p netaddr.IPPrefix) (T, bool) {
+	var value T
+	p = canonicalPrefix(p)
+	v, ok := ipam.GetTree(familyOfIP(p.IP())).Get(p)
+	if !ok {
+		return value, false
+	}
+	d, ok := ipam.getData(v)
+	if !ok || !d.GetMeta().HasIpPrefix() {
+		return value, false
+	}
+	// the prefix of a node is the only key with a mask in its data, the
+	// key is found without formatting the prefix
+	for k, v := range d.GetValue() {
+		if strings.Contains(k, "/") {
+			return v, true
 		}
-		return true, nil
 	}
+	return value, false
+}
 
-	return false, nil
+// PreCheckAddRange adds a range to the dummy tree, to validate if the insertion would be successfull
+// a rejection by the policy is returned as a conflict error
+func (ipam *IpTree[T]) PreCheckAddRange(ra string, value T) (bool, error) {
+	if err := ipam.AddRange(ra, value); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
-// AddRange adds a range to the tree in a very open minded way
-// overlap is not validated as this method, the method assumes validation
+// AddRange adds a range to the tree, the policy of the tree is enforced
+// but overlap is not validated as this method, the method assumes validation
 // was performed before calling it
-func (ipam *IpTree) AddRange(ra string, value interface{}) error {
-	t := ipam.GetTree()
-	_, _, d, err := ipam.validateOverlap(ra)
+func (ipam *IpTree[T]) AddRange(ra string, value T) error {
+	r, err := ParseRange(ra)
 	if err != nil {
-		fmt.Println("error validating overlap")
-		panic(err)
+		ipam.metrics.observe(opAddRange, &err)
+		return err
 	}
-	if d != nil && !d.GetMeta().HasIpRange() {
-		// the higher level range does not overlap
-		// a range should have a parent prefix
-
-		prefixes, err := getPrefixesForRange(ra)
-		if err != nil {
-			return errors.Wrap(err, "cannot get prefixes from range")
-		}
+	return ipam.AddIPRange(r, value)
+}
 
-		// no overlap or errors, so we can insert the data to the tree
-		transaction := true
-		var e error
-		for _, p := range prefixes {
-			_, key, d, err := ipam.validateOverlap(p.String())
-			if err != nil {
-				e = errors.Wrap(err, "error validating overlap")
-				transaction = false
-				break
-			}
-			if key != nil && key.String() == p.String() {
-				// the range aggregate prefix matches with a parent prefix,
-				// -> augment the data
-				d.GetMeta().SetIpRange()
-				d.AddValue(ra, value)
-				if err := t.AddCIDR(p.String(), *d); err != nil {
-					e = errors.Wrap(err, "error adding prefix")
-					transaction = false
-					break
-				}
-			} else {
-				// the range aggregate prefix does NOT match with a parent prefix
-				// -> initialize as a new prefix with the range info
-				v := Data{
-					meta: &Metadata{
-						ipRange: true,
-					},
-					value: map[string]interface{}{ra: value},
-				}
-				if err := t.AddCIDR(p.String(), v); err != nil {
-					e = errors.Wrap(err, "error adding prefix")
-					transaction = false
-					break
-				}
-			}
-		}
-		if !transaction {
-			fmt.Println("Transation failed")
-			return e
-			// todo delete insertions
-		}
+// AddIPRange adds a range to the tree like AddRange
+func (ipam *IpTree[T]) AddIPRange(r netaddr.IPRange, value T) (err error) {
+	defer ipam.metrics.observe(opAddRange, &err)
+	if r, err = canonicalRange(r); err != nil {
+		return err
 	}
-	return nil
+	if err := ipam.checkAddRange(r); err != nil {
+		return err
+	}
+	// the range is stored once in the range index instead of in the nodes
+	// of the prefixes it consists of
+	ra := r.String()
+	old, _ := ipam.rangeIndex(familyOfIP(r.From())).insert(r, ra, value)
+	return ipam.emit(EventAdded, ra, old, value)
 }
 
 // DeleteRange deletes a range from the tree in a very open minded way
 // overlap is not validated as this method, the method assumes validation
-// was performed before calling it
-func (ipam *IpTree) DeleteRange(ra string) error {
-	t := ipam.GetTree()
-
-	prefixes, err := getPrefixesForRange(ra)
+// was performed before calling it, removing a range never violates the
+// policy of the tree
+func (ipam *IpTree[T]) DeleteRange(ra string) error {
+	r, err := ParseRange(ra)
 	if err != nil {
-		return errors.Wrap(err, "cannot get prefixes from range")
+		ipam.metrics.observe(opDeleteRange, &err)
+		return err
 	}
+	return ipam.DeleteIPRange(r)
+}
 
-	// no overlap or errors, so we can insert the data to the tree
-	transaction := true
-	var e error
-	for _, p := range prefixes {
-		_, key, d, err := ipam.validateOverlap(p.String())
-		if err != nil {
-			e = errors.Wrap(err, "error validating overlap")
-			transaction = false
-			break
-		}
-		if key != nil {
-			d.DeleteValue(ra)
-			if d.GetMeta().HasIpAddress() || d.GetMeta().HasIpPrefix() {
-				// the element is used for other information
-				fmt.Println("delete range with other data")
-				// check if other ranges still get mapped
-				if !findOtherRanges(d.GetValue()) {
-					d.GetMeta().ResetIpRange()
-				}
-				// update the data in the tree
-				if err := t.AddCIDR(p.String(), *d); err != nil {
-					e = errors.Wrap(err, "error updating prefix")
-					transaction = false
-					break
-				}
-			} else {
-				fmt.Println("delete range no other rusage")
-				// check if other ranges still get mapped
-				if !findOtherRanges(d.GetValue()) {
-					d.GetMeta().ResetIpRange()
-					_, _, err := t.DeleteCIDR(p.String())
-					if err != nil {
-						e = errors.Wrap(err, "error deleting prefix")
-						transaction = false
-						break
-					}
-				} else {
-					// update the data in the tree
-					if err := t.AddCIDR(p.String(), *d); err != nil {
-						e = errors.Wrap(err, "error updating prefix")
-						transaction = false
-						break
-					}
-				}
-
-			}
-		}
+// DeleteIPRange deletes a range from the tree like DeleteRange
+func (ipam *IpTree[T]) DeleteIPRange(r netaddr.IPRange) (err error) {
+	defer ipam.metrics.observe(opDeleteRange, &err)
+	if r, err = canonicalRange(r); err != nil {
+		return err
 	}
-	if !transaction {
-		fmt.Println("Transation failed")
-		return e
-		// todo delete insertions
+	n, ok := ipam.rangeIndex(familyOfIP(r.From())).delete(r)
+	if ok {
+		var value T
+		return ipam.emit(EventDeleted, n.key, n.value, value)
 	}
 	return nil
 }
 
-func (ipam *IpTree) Parent(r netaddr.IPPrefix) []netaddr.IPPrefix {
-	t := ipam.GetTree()
+func (ipam *IpTree[T]) Parent(r netaddr.IPPrefix) []netaddr.IPPrefix {
+	t := ipam.GetTree(familyOfIP(r.IP()))
 	var result []netaddr.IPPrefix
-	f := func(n *net.IPNet, _ interface{}) bool {
-		pfx, _ := netaddr.FromStdIPNet(n)
+	f := func(pfx netaddr.IPPrefix, _ interface{}) bool {
 		if pfx != r {
 			result = append(result, pfx)
 			return true
@@ -472,68 +466,90 @@ func (ipam *IpTree) Parent(r netaddr.IPPrefix) []netaddr.IPPrefix {
 		return true
 	}
 	result = []netaddr.IPPrefix{}
-	t.WalkMatch(r.IPNet(), f)
+	t.WalkMatch(r, f)
 	return result
 }
 
-func (ipam *IpTree) Parents(r netaddr.IPPrefix) []netaddr.IPPrefix {
-	t := ipam.GetTree()
+func (ipam *IpTree[T]) Parents(r netaddr.IPPrefix) []netaddr.IPPrefix {
+	t := ipam.GetTree(familyOfIP(r.IP()))
 	var result []netaddr.IPPrefix
-	f := func(n *net.IPNet, _ interface{}) bool {
-		pfx, _ := netaddr.FromStdIPNet(n)
+	f := func(pfx netaddr.IPPrefix, _ interface{}) bool {
 		if pfx != r {
 			result = append(result, pfx)
 		}
 		return true
 	}
 	result = []netaddr.IPPrefix{}
-	t.WalkMatch(r.IPNet(), f)
+	t.WalkMatch(r, f)
 	return result
 }
 
-func (ipam *IpTree) Children(r netaddr.IPPrefix) []netaddr.IPPrefix {
-	t := ipam.GetTree()
+func (ipam *IpTree[T]) Children(r netaddr.IPPrefix) []netaddr.IPPrefix {
+	t := ipam.GetTree(familyOfIP(r.IP()))
 	var result []netaddr.IPPrefix
-	f := func(n *net.IPNet, _ interface{}) bool {
-		pfx, _ := netaddr.FromStdIPNet(n)
+	f := func(pfx netaddr.IPPrefix, _ interface{}) bool {
 		if pfx.Bits() > r.Bits() {
 			result = append(result, pfx)
 		}
 		return true
 	}
 	result = []netaddr.IPPrefix{}
-	t.WalkPrefix(r.IPNet(), f)
+	t.WalkPrefix(r, f)
 	return result
 }
 
-func findOtherRanges(data map[string]interface{}) bool {
-	//fmt.Println("findOtherRanges", data)
-	found := false
-	for k := range data {
-		if strings.Contains(k, "-") {
-			found = true
+func main() {
+	grpcAddress := flag.String("grpc-address", "", "serve an ipam tree over grpc on this address")
+	httpAddress := flag.String("http-address", "", "serve an ipam tree as a rest api on this address")
+	pools := flag.String("metric-pools", "", "comma separated prefixes and ranges of which the http server exposes the utilization")
+	controller := flag.Bool("controller", false, "reconcile the ipam resources of the current kubernetes cluster")
+	flag.Parse()
+
+	if *controller {
+		fmt.Println("reconciling ipam resources")
+		if err := RunController(ctrl.SetupSignalHandler(), New[string]()); err != nil {
+			panic(err)
 		}
+		return
 	}
-	return found
-}
 
-func main() {
-	// Creating new Trie in memory
-	ipam := New()
-	// Printing the size of the Radix/Patricia tree
-	fmt.Println("The ipam tree contains", ipam.GetTree().Size(), "prefixes")
+	if *httpAddress != "" {
+		l, err := net.Listen("tcp", *httpAddress)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println("serving ipam over http on", l.Addr())
+		if err := NewHttpServer(New[string](), strings.Split(*pools, ",")...).Serve(l); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	if *grpcAddress != "" {
+		l, err := net.Listen("tcp", *grpcAddress)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println("serving ipam over grpc on", l.Addr())
+		if err := NewGrpcServer(New[string]()).Serve(l); err != nil {
+			panic(err)
+		}
+		return
+	}
 
-	ipamDummy := New()
+	// Creating new Trie in memory
+	ipam := New[string]()
 	// Printing the size of the Radix/Patricia tree
-	fmt.Println("The dummy ipam tree tree contains", ipamDummy.GetTree().Size(), "prefixes")
+	fmt.Println("The ipam tree contains", ipam.Size(), "prefixes")
 
-	cidrs := []map[string]interface{}{
+	cidrs := []map[string]string{
 		{"10.0.0.0/8": "rfc1918"},
 		{"10.0.0.0/24": "super1"},
 		{"10.0.1.0/24": "super2"},
 		{"10.0.0.0/16": "newsuper"}, // without sorting it fails on overlap check due to the fact it matches a /24 and a /8
 		{"10.0.2.0/24": "super3"},
 		{"10.0.255.0/24": "super4"},
+		{"::ffff:10.0.3.0/120": "mapped"}, // ipv4-mapped ipv6 is stored as 10.0.3.0/24
 		{"3000::/32": "ipv6"},
 		{"10.0.0.0-10.0.0.255": "range1"},
 		//{"10.0.0.3-10.0.0.178": "range1.1"},
@@ -549,8 +565,8 @@ func main() {
 		for ipitem, value := range m {
 			fmt.Println("@@@@@@@@@@@@", ipitem, "@@@@@@@@@@@@")
 			if strings.Contains(ipitem, "-") {
-				ok, err := ipamDummy.PreCheckAddition(ipitem)
-				if err != nil {
+				ok, err := ipam.PreCheckAddition(ipitem)
+				if err != nil && !IsConflict(err) {
 					fmt.Println(err)
 					panic(err)
 				}
@@ -560,12 +576,12 @@ func main() {
 						panic(err)
 					}
 				} else {
-					fmt.Println("cannot add range", ipitem)
+					fmt.Println("cannot add range", ipitem, err)
 				}
 
 			} else {
-				ok, err := ipamDummy.PreCheckAddition(ipitem)
-				if err != nil {
+				ok, err := ipam.PreCheckAddition(ipitem)
+				if err != nil && !IsConflict(err) {
 					fmt.Println(err)
 					panic(err)
 				}
@@ -575,24 +591,24 @@ func main() {
 						panic(err)
 					}
 				} else {
-					fmt.Println("cannot add prefix", ipitem)
+					fmt.Println("cannot add prefix", ipitem, err)
 				}
 			}
 			fmt.Println("---------------------------------")
-			fmt.Println("The tree contains", ipam.GetTree().Size(), "prefixes")
-			ipam.GetTree().Walk(nil, callback)
+			fmt.Println("The tree contains", ipam.Size(), "prefixes")
+			ipam.Walk(callback[string])
 			fmt.Println("---------------------------------")
 		}
 	}
 
 	fmt.Println("---------------------------------")
-	fmt.Println("The tree contains", ipam.GetTree().Size(), "prefixes")
-	ipam.GetTree().Walk(nil, callback)
+	fmt.Println("The tree contains", ipam.Size(), "prefixes")
+	ipam.Walk(callback[string])
 	fmt.Println("---------------------------------")
 
 	r := "10.0.0.0-10.0.0.255"
-	ok, err := ipamDummy.PreCheckDeletion(r)
-	if err != nil {
+	ok, err := ipam.PreCheckDeletion(r)
+	if err != nil && !IsConflict(err) {
 		panic(err)
 	}
 	if ok {
@@ -605,8 +621,8 @@ func main() {
 	}
 
 	fmt.Println("---------------------------------")
-	fmt.Println("The tree contains", ipam.GetTree().Size(), "prefixes")
-	ipam.GetTree().Walk(nil, callback)
+	fmt.Println("The tree contains", ipam.Size(), "prefixes")
+	ipam.Walk(callback[string])
 	fmt.Println("---------------------------------")
 	/*
 		success, err := ipam.Validate(cidrs)
@@ -706,108 +722,3 @@ func main() {
 	*/
 
 }
-
-func netValidateIpNet(r *netaddr.IPPrefix) (start, end netaddr.IP, isV4 bool, err error) {
-	if r == nil {
-		err = errors.New("IP network is nil")
-		return
-	}
-	return netValidatePrefix(r)
-}
-
-func netValidatePrefix(r *netaddr.IPPrefix) (start, end netaddr.IP, isV4 bool, err error) {
-	if r.IP().Is4() {
-		ra := r.Range()
-		start = ra.From()
-		end = ra.To()
-		isV4 = true
-	} else if r.IP().Is6() {
-		ra := r.Range()
-		start = ra.From()
-		end = ra.To()
-	} else {
-		err = fmt.Errorf("invalid IP Prefix: %s", r.String())
-	}
-	return
-}
-
-func netValidateIpRange(r *netaddr.IPRange) (start, end netaddr.IP, isV4 bool, err error) {
-	if r.From().Is4() && r.To().Is4() {
-		start = r.From()
-		end = r.To()
-		isV4 = true
-	} else if r.From().Is6() && r.To().Is6() {
-		start = r.From()
-		end = r.To()
-	} else {
-		err = fmt.Errorf("invalid IP Range: %s", r.String())
-	}
-	return
-}
-
-func AggregatePrefixFromRange(r *netaddr.IPRange) (*netaddr.IPPrefix, error) {
-	start := r.From().IPAddr().IP
-	end := r.To().IPAddr().IP
-	// find the byte where there is a delta can be 0, 1, 2, 3
-	idx := -1
-	for i := 0; i < len(start); i++ {
-		if start[i] != end[i] {
-			idx = i
-			break
-		}
-	}
-	//fmt.Println("Byte", idx)
-	// find the mask based on amount of addresses
-	uppermask := idx * 8
-	delta := end[idx] - start[idx]
-	d := int(delta)
-	//fmt.Println(d)
-	idx2 := -1
-	for i := 1; i <= 8; i++ {
-		if d/int((math.Pow(2, float64(i)))) < 1 {
-			idx2 = i
-			break
-		}
-	}
-	//fmt.Println(idx2)
-	uppermask += 8 - idx2
-	//fmt.Println("Mask", uppermask)
-
-	startByte := int(int(start[idx])/int((math.Pow(2, float64(idx2))))) * int((math.Pow(2, float64(idx2))))
-	//fmt.Println("StartByte", startByte)
-
-	if r.From().Is4() {
-		var ip [4]byte
-		for i := 0; i < len(start); i++ {
-			switch {
-			case i < idx:
-				ip[i] = start[i]
-			case i == idx:
-				ip[i] = byte(startByte)
-			default:
-				ip[i] = 0
-			}
-		}
-
-		p := netaddr.IPPrefixFrom(netaddr.IPFrom4(ip), uint8(uppermask))
-		return &p, nil
-	}
-
-	if r.From().Is6() {
-		var ip [16]byte
-		for i := 0; i < len(start); i++ {
-			switch {
-			case i < idx:
-				ip[i] = start[i]
-			case i == idx:
-				ip[i] = byte(startByte)
-			default:
-				ip[i] = 0
-			}
-		}
-
-		p := netaddr.IPPrefixFrom(netaddr.IPv6Raw(ip), uint8(uppermask))
-		return &p, nil
-	}
-	return nil, errors.New("wrong ip version")
-}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"inet.af/netaddr"
+)
+
+// owner is a structured value stored in the tree by the generic api tests
+type owner struct {
+	Name string
+	Vlan int
+}
+
+func TestGenericValue(t *testing.T) {
+	ipam := New[owner]()
+	if err := ipam.AddPrefix("10.0.0.0/24", owner{Name: "net", Vlan: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ipam.AddRange("10.0.0.10-10.0.0.20", owner{Name: "pool", Vlan: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ipam.ClaimIP("10.0.0.1", owner{Name: "gw", Vlan: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ipam.AddPrefix("2001:db8::/64", owner{Name: "net6", Vlan: 20}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		get  func(string) (owner, bool)
+		key  string
+		want owner
+		ok   bool
+	}{
+		{name: "prefix", get: ipam.GetPrefix, key: "10.0.0.0/24", want: owner{Name: "net", Vlan: 10}, ok: true},
+		{name: "ipv6 prefix", get: ipam.GetPrefix, key: "2001:db8::/64", want: owner{Name: "net6", Vlan: 20}, ok: true},
+		{name: "range", get: ipam.GetRange, key: "10.0.0.10-10.0.0.20", want: owner{Name: "pool", Vlan: 10}, ok: true},
+		{name: "ip address", get: ipam.GetIP, key: "10.0.0.1", want: owner{Name: "gw", Vlan: 10}, ok: true},
+		{name: "missing prefix", get: ipam.GetPrefix, key: "10.0.1.0/24"},
+		{name: "missing range", get: ipam.GetRange, key: "10.0.0.30-10.0.0.40"},
+		{name: "missing ip address", get: ipam.GetIP, key: "10.0.0.2"},
+		{name: "invalid prefix", get: ipam.GetPrefix, key: "foo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.get(tt.key)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("get %s = %+v %v, want %+v %v", tt.key, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+
+	// adding an existing prefix replaces its value
+	if err := ipam.AddPrefix("10.0.0.0/24", owner{Name: "net", Vlan: 30}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ipam.GetPrefix("10.0.0.0/24"); got.Vlan != 30 {
+		t.Errorf("GetPrefix() = %+v, want vlan 30", got)
+	}
+
+	got := map[string]owner{}
+	for _, e := range ipam.Export() {
+		got[e.Key] = e.Value
+		if e.Revision == 0 {
+			t.Errorf("Export() entry %s has no revision", e.Key)
+		}
+	}
+	want := map[string]owner{
+		"10.0.0.0/24":         {Name: "net", Vlan: 30},
+		"10.0.0.10-10.0.0.20": {Name: "pool", Vlan: 10},
+		"10.0.0.1":            {Name: "gw", Vlan: 10},
+		"2001:db8::/64":       {Name: "net6", Vlan: 20},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Export() = %v, want %v", got, want)
+	}
+
+	// the walk visits the ipv4 nodes before the ipv6 nodes and hands out the
+	// typed values held by each node
+	walked := map[string]owner{}
+	families := []Family{}
+	ipam.Walk(func(p netaddr.IPPrefix, d *Data[owner]) bool {
+		f := familyOfIP(p.IP())
+		if len(families) == 0 || families[len(families)-1] != f {
+			families = append(families, f)
+		}
+		for k, v := range d.GetValue() {
+			walked[k] = v
+		}
+		return true
+	})
+	if !reflect.DeepEqual(families, Families) {
+		t.Errorf("Walk() families = %v, want %v", families, Families)
+	}
+	for _, k := range []string{"10.0.0.0/24", "10.0.0.1", "2001:db8::/64"} {
+		if walked[k] != want[k] {
+			t.Errorf("Walk() %s = %+v, want %+v", k, walked[k], want[k])
+		}
+	}
+
+	// the walk stops when fn returns false
+	n := 0
+	ipam.Walk(func(netaddr.IPPrefix, *Data[owner]) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Errorf("Walk() visited %d nodes after stop, want 1", n)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// operation names of the operations counter
+const (
+	opAddPrefix    = "add_prefix"
+	opDeletePrefix = "delete_prefix"
+	opAddRange     = "add_range"
+	opDeleteRange  = "delete_range"
+	opAllocate     = "allocate"
+	opClaim        = "claim"
+	opRelease      = "release"
+	opReap         = "reap"
+)
+
+// durationBuckets range from a microsecond to a quarter of a second, a
+// validation or allocation walks the tree in memory
+var durationBuckets = prometheus.ExponentialBuckets(1e-6, 4, 10)
+
+var (
+	poolTotalDesc = prometheus.NewDesc("ipam_pool_addresses",
+		"Number of ip addresses of a prefix or range.",
+		[]string{"pool", "family"}, nil)
+	poolUsedDesc = prometheus.NewDesc("ipam_pool_addresses_used",
+		"Number of ip addresses of a prefix or range that are allocated, reserved or delegated to a more specific prefix or range.",
+		[]string{"pool", "family"}, nil)
+	poolFreeDesc = prometheus.NewDesc("ipam_pool_addresses_free",
+		"Number of ip addresses of a prefix or range that can be allocated.",
+		[]string{"pool", "family"}, nil)
+)
+
+// metrics holds the operation counters and latency histograms of a tree
+type metrics struct {
+	operations      *prometheus.CounterVec
+	validateOverlap prometheus.Histogram
+	allocation      prometheus.Histogram
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ipam_operations_total",
+			Help: "Number of add, delete and allocate operations by outcome.",
+		}, []string{"operation", "outcome"}),
+		validateOverlap: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "ipam_validate_overlap_duration_seconds",
+			Help:    "Latency of the overlap validation of a prefix or range that is added to the tree.",
+			Buckets: durationBuckets,
+		}),
+		allocation: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "ipam_allocation_duration_seconds",
+			Help:    "Latency of the allocation of an ip address.",
+			Buckets: durationBuckets,
+		}),
+	}
+}
+
+// observe counts operation op by the outcome of err, it is deferred with a
+// pointer to the named error result of the operation
+func (m *metrics) observe(op string, err *error) {
+	if m == nil || m.operations == nil {
+		return
+	}
+	m.operations.WithLabelValues(op, ErrorReason(*err)).Inc()
+}
+
+// observeValidateOverlap records the latency of a validation started at start
+func (m *metrics) observeValidateOverlap(start time.Time) {
+	if m == nil || m.validateOverlap == nil {
+		return
+	}
+	m.validateOverlap.Observe(time.Since(start).Seconds())
+}
+
+// observeAllocation records the latency of an allocation started at start
+func (m *metrics) observeAllocation(start time.Time) {
+	if m == nil || m.allocation == nil {
+		return
+	}
+	m.allocation.Observe(time.Since(start).Seconds())
+}
+
+// collector exposes the utilization of the pools and the metrics of a tree
+type collector[T any] struct {
+	ipam  *IpTree[T]
+	l     sync.Locker
+	pools []string
+}
+
+// Collector returns a prometheus collector with the total, used and free
+// addresses of the prefixes and ranges in pools and the operation counters
+// and latency histograms of the tree. The utilization of a pool walks the
+// entries inside it when the collector is scraped, so it is reported for
+// the given pools only rather than for every entry of the tree, a pool that
+// is not in the tree is skipped. l guards the tree when it is shared with
+// other goroutines and can be nil. Use prometheus.WrapRegistererWith to
+// register the collectors of multiple trees
+func (ipam *IpTree[T]) Collector(l sync.Locker, pools ...string) prometheus.Collector {
+	return &collector[T]{
+		ipam:  ipam,
+		l:     l,
+		pools: pools,
+	}
+}
+
+func (c *collector[T]) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolTotalDesc
+	ch <- poolUsedDesc
+	ch <- poolFreeDesc
+	if m := c.ipam.metrics; m != nil {
+		m.operations.Describe(ch)
+		m.validateOverlap.Describe(ch)
+		m.allocation.Describe(ch)
+	}
+}
+
+func (c *collector[T]) Collect(ch chan<- prometheus.Metric) {
+	if c.l != nil {
+		c.l.Lock()
+		defer c.l.Unlock()
+	}
+	for _, pool := range c.pools {
+		key, _, err := Canonicalize(pool)
+		if err != nil || !strings.ContainsAny(key, "/-") {
+			continue
+		}
+		r, err := keyRange(key)
+		if err != nil {
+			continue
+		}
+		free, err := c.ipam.freeIPs(key)
+		if err != nil {
+			continue
+		}
+		total := AddressCount(r)
+		available := big.NewInt(0)
+		for _, fr := range free.Ranges() {
+			available.Add(available, AddressCount(fr))
+		}
+		used := new(big.Int).Sub(total, available)
+		family := familyOfIP(r.From()).String()
+		ch <- prometheus.MustNewConstMetric(poolTotalDesc, prometheus.GaugeValue, bigFloat(total), key, family)
+		ch <- prometheus.MustNewConstMetric(poolUsedDesc, prometheus.GaugeValue, bigFloat(used), key, family)
+		ch <- prometheus.MustNewConstMetric(poolFreeDesc, prometheus.GaugeValue, bigFloat(available), key, family)
+	}
+	if m := c.ipam.metrics; m != nil {
+		m.operations.Collect(ch)
+		m.validateOverlap.Collect(ch)
+		m.allocation.Collect(ch)
+	}
+}
+
+// bigFloat converts an address count to a gauge value, large ipv6 pools
+// lose precision
+func bigFloat(n *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(n).Float64()
+	return f
+}
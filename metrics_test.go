@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollectorPools(t *testing.T) {
+	ipam := New[string]()
+	for _, p := range []string{"10.0.0.0/24", "10.0.1.0/24"} {
+		if err := ipam.AddPrefix(p, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ipam.AddRange("10.0.0.10-10.0.0.19", "pool"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ipam.ClaimIP("10.0.0.5", "host"); err != nil {
+		t.Fatal(err)
+	}
+	// the second prefix, the address and the missing pool are not reported
+	c := ipam.Collector(nil, "10.0.0.0/24", "10.0.0.10-10.0.0.19", "10.0.0.5", "10.1.0.0/24")
+	want := `
+# HELP ipam_pool_addresses_used Number of ip addresses of a prefix or range that are allocated, reserved or delegated to a more specific prefix or range.
+# TYPE ipam_pool_addresses_used gauge
+ipam_pool_addresses_used{family="ipv4",pool="10.0.0.0/24"} 13
+ipam_pool_addresses_used{family="ipv4",pool="10.0.0.10-10.0.0.19"} 0
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want), "ipam_pool_addresses_used"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateOverlapObserved(t *testing.T) {
+	ipam := New[string]()
+	count := func() uint64 {
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(ipam.Collector(nil))
+		mfs, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, mf := range mfs {
+			if mf.GetName() == "ipam_validate_overlap_duration_seconds" {
+				return mf.GetMetric()[0].GetHistogram().GetSampleCount()
+			}
+		}
+		return 0
+	}
+	// a pre-check validates on a dummy tree and is not observed
+	if ok, err := ipam.PreCheckAddition("10.0.0.0/24"); !ok {
+		t.Fatal(err)
+	}
+	if n := count(); n != 0 {
+		t.Errorf("pre-check observed %d validations", n)
+	}
+	if err := ipam.AddPrefix("10.0.0.0/24", "net"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ipam.AddRange("10.0.0.10-10.0.0.19", "pool"); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 2 {
+		t.Errorf("observed %d validations, want 2", n)
+	}
+}
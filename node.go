@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+
+	"inet.af/netaddr"
+)
+
+// addValue adds the value with key to the node of prefix p, the node is
+// created when it does not exist and set marks the kind of the value in the
+// metadata of the node
+func (ipam *IpTree[T]) addValue(p netaddr.IPPrefix, k string, value T, set func(*Metadata)) error {
+	t := ipam.GetTree(familyOfIP(p.IP()))
+	v, ok := t.Get(p)
+	d, found := ipam.getData(v)
+	if !ok || !found {
+		d = &Data[T]{
+			meta:  &Metadata{},
+			value: map[string]T{},
+		}
+	}
+	set(d.GetMeta())
+	d.AddValue(k, value)
+	return t.Add(p, d)
+}
+
+// deleteValue removes the value with key from the node of prefix p, the
+// node is removed when no values are left
+func (ipam *IpTree[T]) deleteValue(p netaddr.IPPrefix, k string) error {
+	t := ipam.GetTree(familyOfIP(p.IP()))
+	v, ok := t.Get(p)
+	if !ok {
+		return nil
+	}
+	d, ok := ipam.getData(v)
+	if !ok {
+		return nil
+	}
+	d.DeleteValue(k)
+	if len(d.GetValue()) == 0 {
+		t.Delete(p)
+		return nil
+	}
+	switch {
+	case strings.Contains(k, "/"):
+		d.GetMeta().ResetIpPrefix()
+	default:
+		d.GetMeta().ResetIpAddress()
+	}
+	return t.Add(p, d)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// OpenAPI returns the openapi document of the http api, it is generated
+// from the routes of the handlers
+func (s *HttpServer) OpenAPI() map[string]interface{} {
+	paths := map[string]interface{}{}
+	for _, rt := range s.routes() {
+		path := strings.ReplaceAll(rt.path, "...}", "}")
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[path] = item
+		}
+
+		params := []interface{}{}
+		for _, seg := range strings.Split(path, "/") {
+			if strings.HasPrefix(seg, "{") {
+				params = append(params, map[string]interface{}{
+					"name":     strings.Trim(seg, "{}"),
+					"in":       "path",
+					"required": true,
+					"schema":   map[string]interface{}{"type": "string"},
+				})
+			}
+		}
+		for _, q := range rt.query {
+			params = append(params, map[string]interface{}{
+				"name":   q,
+				"in":     "query",
+				"schema": map[string]interface{}{"type": "string"},
+			})
+		}
+
+		response := map[string]interface{}{
+			"description": http.StatusText(rt.status),
+		}
+		if rt.response != nil {
+			response["content"] = jsonContent(rt.response)
+		}
+		op := map[string]interface{}{
+			"summary":     rt.summary,
+			"operationId": operationID(rt.method, path),
+			"parameters":  params,
+			"responses": map[string]interface{}{
+				strconv.Itoa(rt.status): response,
+				"default": map[string]interface{}{
+					"description": "error",
+					"content":     jsonContent(HttpErrorResponse{}),
+				},
+			},
+		}
+		if rt.request != nil {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(rt.request),
+			}
+		}
+		item[strings.ToLower(rt.method)] = op
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "lpm ipam",
+			"version": "v1",
+		},
+		"paths": paths,
+	}
+}
+
+func jsonContent(v interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{
+			"schema": jsonSchema(reflect.TypeOf(v)),
+		},
+	}
+}
+
+// jsonSchema returns the schema of the json encoding of type t
+func jsonSchema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return jsonSchema(t.Elem())
+	case reflect.Struct:
+		props := map[string]interface{}{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if name == "" {
+				name = f.Name
+			}
+			if name == "-" {
+				continue
+			}
+			props[name] = jsonSchema(f.Type)
+		}
+		return map[string]interface{}{"type": "object", "properties": props}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": jsonSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": jsonSchema(t.Elem())}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	return map[string]interface{}{"type": "string"}
+}
+
+// operationID returns e.g. getPrefixesPrefix for GET /prefixes/{prefix}
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, seg := range strings.Split(path, "/") {
+		seg = strings.Trim(seg, "{}")
+		if seg != "" {
+			id += strings.ToUpper(seg[:1]) + seg[1:]
+		}
+	}
+	return id
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"inet.af/netaddr"
+)
+
+// Plan is the ordered list of changes that brings the prefixes and ranges
+// of the tree to a desired state, see planPhase for the order of the
+// changes. Children are deleted before their parents and parents are added
+// before their children
+type Plan[T any] struct {
+	// Revision is the revision of the tree the plan was made for
+	Revision uint64
+	Changes  []Change[T]
+}
+
+// String returns the plan in a human readable form, one change per line
+func (p *Plan[T]) String() string {
+	var sb strings.Builder
+	for _, c := range p.Changes {
+		switch c.Type {
+		case EventAdded:
+			fmt.Fprintf(&sb, "+ %s %v\n", c.Key, c.After)
+		case EventDeleted:
+			fmt.Fprintf(&sb, "- %s %v\n", c.Key, c.Before)
+		case EventUpdated:
+			fmt.Fprintf(&sb, "~ %s %v -> %v\n", c.Key, c.Before, c.After)
+		}
+	}
+	fmt.Fprintf(&sb, "%d to add, %d to change, %d to delete\n",
+		p.count(EventAdded), p.count(EventUpdated), p.count(EventDeleted))
+	return sb.String()
+}
+
+func (p *Plan[T]) count(t EventType) int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Type == t {
+			n++
+		}
+	}
+	return n
+}
+
+// Plan compares the desired prefixes and ranges with the tree and returns
+// the changes to apply, allocated ip addresses are not managed by a plan
+func (ipam *IpTree[T]) Plan(desired []Entry[T]) (*Plan[T], error) {
+	want := map[string]T{}
+	for _, e := range desired {
+		k, _, err := Canonicalize(e.Key)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.Contains(k, "/") && !strings.Contains(k, "-") {
+			return nil, errors.Wrapf(ErrInvalid, "%s is not a prefix or range", k)
+		}
+		if _, ok := want[k]; ok {
+			return nil, errors.Wrapf(ErrInvalid, "%s is desired more than once", k)
+		}
+		want[k] = e.Value
+	}
+
+	plan := &Plan[T]{
+		Revision: ipam.Revision(),
+		Changes:  []Change[T]{},
+	}
+	have := map[string]T{}
+	for _, e := range ipam.Export() {
+		if !strings.Contains(e.Key, "/") && !e.IsRange() {
+			continue
+		}
+		have[e.Key] = e.Value
+		v, ok := want[e.Key]
+		switch {
+		case !ok:
+			plan.Changes = append(plan.Changes, Change[T]{Type: EventDeleted, Key: e.Key, Before: e.Value})
+		case !reflect.DeepEqual(v, e.Value):
+			plan.Changes = append(plan.Changes, Change[T]{Type: EventUpdated, Key: e.Key, Before: e.Value, After: v})
+		}
+	}
+	for k, v := range want {
+		if _, ok := have[k]; !ok {
+			plan.Changes = append(plan.Changes, Change[T]{Type: EventAdded, Key: k, After: v})
+		}
+	}
+
+	sort.SliceStable(plan.Changes, func(i, j int) bool {
+		ci, cj := plan.Changes[i], plan.Changes[j]
+		if pi, pj := planPhase(ci), planPhase(cj); pi != pj {
+			return pi < pj
+		}
+		di, dj := planDepth(ci.Key), planDepth(cj.Key)
+		if di != dj {
+			if ci.Type == EventDeleted {
+				return di > dj
+			}
+			return di < dj
+		}
+		return ci.Key < cj.Key
+	})
+	return plan, nil
+}
+
+// planPhase orders the changes of a plan such that every intermediate tree
+// is valid: removed ranges go first as they may hold a new prefix, new
+// prefixes are added before the removed prefixes are deleted such that the
+// ranges that stay move to their new parent, and new ranges go last as they
+// may need a new parent or overlap a removed prefix
+func planPhase[T any](c Change[T]) int {
+	isRange := strings.Contains(c.Key, "-")
+	switch {
+	case c.Type == EventDeleted && isRange:
+		return 0
+	case c.Type == EventAdded && !isRange:
+		return 1
+	case c.Type == EventUpdated:
+		return 2
+	case c.Type == EventDeleted:
+		return 3
+	}
+	return 4
+}
+
+// planDepth orders the keys of a plan like SortPrefixes, a range is deeper
+// than any prefix as it needs a parent prefix
+func planDepth(k string) int {
+	if p, err := netaddr.ParseIPPrefix(k); err == nil {
+		return int(p.Bits())
+	}
+	return 255
+}
+
+// Apply executes the changes of the plan, the changes are reverted when
+// one of them fails such that the tree is either fully changed or not at
+// all. A plan made for another revision of the tree is rejected with a
+// revision conflict as the tree changed since it was planned
+func (ipam *IpTree[T]) Apply(plan *Plan[T]) error {
+	if rev := ipam.Revision(); rev != plan.Revision {
+		return errors.Wrapf(ErrRevisionConflict, "plan is for revision %d, tree is at revision %d", plan.Revision, rev)
+	}
+	for i, c := range plan.Changes {
+		if err := ipam.applyChange(c); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				if rerr := ipam.applyChange(revertChange(plan.Changes[j])); rerr != nil {
+					return errors.Wrapf(rerr, "cannot revert %s after %s failed with %v", plan.Changes[j].Key, c.Key, err)
+				}
+			}
+			return errors.Wrapf(err, "cannot apply %s %s", c.Type, c.Key)
+		}
+	}
+	return nil
+}
+
+// applyChange adds, updates or deletes the prefix or range of change c
+func (ipam *IpTree[T]) applyChange(c Change[T]) error {
+	isRange := strings.Contains(c.Key, "-")
+	switch c.Type {
+	case EventAdded:
+		if ok, err := ipam.PreCheckAddition(c.Key); !ok {
+			if err == nil {
+				err = errors.Wrapf(ErrOverlap, "%s is rejected by the policy", c.Key)
+			}
+			return err
+		}
+		if isRange {
+			return ipam.AddRange(c.Key, c.After)
+		}
+		return ipam.AddPrefix(c.Key, c.After)
+	case EventUpdated:
+		if isRange {
+			return ipam.updateRange(c.Key, c.After)
+		}
+		return ipam.AddPrefix(c.Key, c.After)
+	case EventDeleted:
+		if isRange {
+			return ipam.DeleteRange(c.Key)
+		}
+		// children that stay in the desired state move to the parent
+		_, err := ipam.DeletePrefixWithOptions(c.Key, DeleteOptions{Mode: DeleteReparent})
+		return err
+	}
+	return errors.Errorf("unknown change %s", c.Type)
+}
+
+// revertChange returns the change that undoes change c
+func revertChange[T any](c Change[T]) Change[T] {
+	switch c.Type {
+	case EventAdded:
+		c.Type = EventDeleted
+	case EventDeleted:
+		c.Type = EventAdded
+	}
+	c.Before, c.After = c.After, c.Before
+	return c
+}
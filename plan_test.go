@@ -0,0 +1,98 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestApply(t *testing.T) {
+	ipam := New[string]()
+	if err := ipam.AddPrefix("10.0.0.0/16", "net"); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := ipam.Plan([]Entry[string]{
+		{Key: "10.0.0.0/16", Value: "net"},
+		{Key: "10.0.1.0/24", Value: "sub"},
+		{Key: "10.0.2.10-10.0.2.20", Value: "pool"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(plan.Changes); n != 2 {
+		t.Fatalf("plan has %d changes, want 2:\n%s", n, plan)
+	}
+	if err := ipam.Apply(plan); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.0/16", "10.0.1.0/24", "10.0.2.10-10.0.2.20"}
+	if got := exportKeys(ipam); !reflect.DeepEqual(got, want) {
+		t.Errorf("entries %v, want %v", got, want)
+	}
+	if err := ipam.Apply(plan); errors.Cause(err) != ErrRevisionConflict {
+		t.Errorf("stale plan returned %v, want %v", err, ErrRevisionConflict)
+	}
+}
+
+// a change rejected by the policy fails the plan and reverts the changes
+// that were applied before it
+func TestApplyRejected(t *testing.T) {
+	ipam := New[string]()
+	for _, p := range []string{"10.0.0.0/16", "10.0.1.0/24"} {
+		if err := ipam.AddPrefix(p, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := exportKeys(ipam)
+	plan := &Plan[string]{
+		Revision: ipam.Revision(),
+		Changes: []Change[string]{
+			{Type: EventAdded, Key: "10.0.2.0/24", After: "new"},
+			{Type: EventAdded, Key: "10.0.2.10-10.0.2.20", After: "pool"},
+			// the range holds the prefix
+			{Type: EventAdded, Key: "10.0.2.16/30", After: "inside"},
+		},
+	}
+	err := ipam.Apply(plan)
+	if err == nil || !IsConflict(err) {
+		t.Fatalf("apply returned %v, want a conflict", err)
+	}
+	if got := exportKeys(ipam); !reflect.DeepEqual(got, want) {
+		t.Errorf("entries %v, want %v", got, want)
+	}
+}
+
+// a range that stays moves to a new parent prefix that replaces its old
+// parent, the new parent is added before the old one is deleted
+func TestApplyReparent(t *testing.T) {
+	ipam := New[string]()
+	if err := ipam.AddPrefix("10.0.0.0/24", "old"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ipam.AddRange("10.0.0.10-10.0.0.20", "pool"); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := ipam.Plan([]Entry[string]{
+		{Key: "10.0.0.0/16", Value: "new"},
+		{Key: "10.0.0.10-10.0.0.20", Value: "pool"},
+		{Key: "10.0.1.10-10.0.1.20", Value: "pool2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{}
+	for _, c := range plan.Changes {
+		keys = append(keys, c.Key)
+	}
+	if want := []string{"10.0.0.0/16", "10.0.0.0/24", "10.0.1.10-10.0.1.20"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("plan changes %v, want %v", keys, want)
+	}
+	if err := ipam.Apply(plan); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.0/16", "10.0.0.10-10.0.0.20", "10.0.1.10-10.0.1.20"}
+	if got := exportKeys(ipam); !reflect.DeepEqual(got, want) {
+		t.Errorf("entries %v, want %v", got, want)
+	}
+}
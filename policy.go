@@ -0,0 +1,263 @@
+package main
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"inet.af/netaddr"
+)
+
+// conflict errors returned when a change is rejected by the policy
+var (
+	ErrOverlap     = errors.New("overlap")
+	ErrInsideRange = errors.New("inside range")
+	ErrNestedRange = errors.New("nested range")
+	ErrNoParent    = errors.New("no parent prefix")
+	ErrHasChildren = errors.New("has children")
+)
+
+// IsConflict returns true if the error is a rejection by the policy
+func IsConflict(err error) bool {
+	switch errors.Cause(err) {
+	case ErrOverlap, ErrInsideRange, ErrNestedRange, ErrNoParent, ErrHasChildren, ErrRevisionConflict:
+		return true
+	}
+	return false
+}
+
+// ErrorReason returns a short reason for an error of the ipam tree, it is
+// used to report the outcome of an operation to api clients
+func ErrorReason(err error) string {
+	switch errors.Cause(err) {
+	case nil:
+		return "ok"
+	case ErrOverlap:
+		return "overlap"
+	case ErrInsideRange:
+		return "inside-range"
+	case ErrNestedRange:
+		return "nested-range"
+	case ErrNoParent:
+		return "no-parent"
+	case ErrHasChildren:
+		return "has-children"
+	case ErrRevisionConflict:
+		return "revision-conflict"
+	case ErrNotFound:
+		return "not-found"
+	case ErrExhausted:
+		return "exhausted"
+	case ErrInvalid, ErrMixedFamily:
+		return "parse-error"
+	}
+	return "internal"
+}
+
+// Policy defines which prefixes and ranges can be combined in the tree,
+// the zero value is the most restrictive policy:
+// - ranges may not overlap each other
+// - a range needs a parent prefix
+// - a prefix cannot be added inside a range
+type Policy struct {
+	// AllowNestedRanges allows a range that is fully contained in another range
+	AllowNestedRanges bool
+	// AllowRangeWithoutParent allows a range that is not covered by a prefix
+	AllowRangeWithoutParent bool
+	// AllowPrefixInRange allows a prefix that is fully contained in a range
+	AllowPrefixInRange bool
+	// AllowPrefixEqualRange allows a prefix that holds exactly the same
+	// addresses as a range
+	AllowPrefixEqualRange bool
+}
+
+type options struct {
+	policy       Policy
+	eventHistory int
+	clock        Clock
+	backend      func() Backend
+}
+
+// Option configures the tree created by New
+type Option func(*options)
+
+// WithPolicy sets the policy that is enforced by the add, delete and
+// pre-check methods of the tree
+func WithPolicy(p Policy) Option {
+	return func(o *options) {
+		o.policy = p
+	}
+}
+
+// GetPolicy returns the policy of the tree
+func (ipam *IpTree[T]) GetPolicy() Policy {
+	return ipam.opts.policy
+}
+
+// checkAddPrefix validates the addition of the canonical prefix p against
+// the ranges in the tree
+func (ipam *IpTree[T]) checkAddPrefix(pfx netaddr.IPPrefix) error {
+	defer ipam.metrics.observeValidateOverlap(time.Now())
+	policy := ipam.GetPolicy()
+	pr := pfx.Range()
+	for _, r := range ipam.overlappingRanges(pr) {
+		switch {
+		case r == pr:
+			if !policy.AllowPrefixEqualRange && !policy.AllowPrefixInRange {
+				return errors.Wrapf(ErrInsideRange, "prefix %s equals range %s", pfx, r)
+			}
+		case rangeContains(r, pr):
+			if !policy.AllowPrefixInRange {
+				return errors.Wrapf(ErrInsideRange, "prefix %s is inside range %s", pfx, r)
+			}
+		case rangeContains(pr, r):
+			// the prefix is a parent of the range
+		default:
+			return errors.Wrapf(ErrOverlap, "prefix %s overlaps range %s", pfx, r)
+		}
+	}
+	return nil
+}
+
+// checkAddRange validates the addition of the canonical range ra against
+// the prefixes and ranges in the tree
+func (ipam *IpTree[T]) checkAddRange(r netaddr.IPRange) error {
+	defer ipam.metrics.observeValidateOverlap(time.Now())
+	policy := ipam.GetPolicy()
+	prefixes, ranges := ipam.overlapping(r)
+	parent := false
+	for _, p := range prefixes {
+		pr := p.Range()
+		switch {
+		case pr == r:
+			// the same rule as for a prefix that is added to the range
+			if !policy.AllowPrefixEqualRange && !policy.AllowPrefixInRange {
+				return errors.Wrapf(ErrInsideRange, "prefix %s would equal range %s", p, r)
+			}
+			parent = true
+		case rangeContains(pr, r):
+			parent = true
+		case rangeContains(r, pr):
+			if !policy.AllowPrefixInRange {
+				return errors.Wrapf(ErrInsideRange, "prefix %s would be inside range %s", p, r)
+			}
+		default:
+			return errors.Wrapf(ErrOverlap, "range %s overlaps prefix %s", r, p)
+		}
+	}
+	if !parent && !policy.AllowRangeWithoutParent {
+		return errors.Wrapf(ErrNoParent, "range %s", r)
+	}
+	for _, o := range ranges {
+		switch {
+		case o == r:
+			return errors.Wrapf(ErrOverlap, "range %s exists", r)
+		case rangeContains(o, r) || rangeContains(r, o):
+			if !policy.AllowNestedRanges {
+				return errors.Wrapf(ErrNestedRange, "range %s and range %s", r, o)
+			}
+		default:
+			return errors.Wrapf(ErrOverlap, "range %s overlaps range %s", r, o)
+		}
+	}
+	return nil
+}
+
+// checkDeletePrefix validates that the deletion of the canonical prefix p
+// does not leave a range without a parent prefix
+func (ipam *IpTree[T]) checkDeletePrefix(pfx netaddr.IPPrefix) error {
+	if ipam.GetPolicy().AllowRangeWithoutParent {
+		return nil
+	}
+	for _, r := range ipam.overlappingRanges(pfx.Range()) {
+		if !rangeContains(pfx.Range(), r) {
+			continue
+		}
+		if !ipam.hasParentPrefix(r, pfx) {
+			return errors.Wrapf(ErrNoParent, "deleting prefix %s leaves range %s", pfx, r)
+		}
+	}
+	return nil
+}
+
+// hasParentPrefix returns true if a prefix in the tree other than skip holds
+// all addresses of the range r. Such a prefix holds the covering prefix of
+// r, so only the parents of the covering prefix are visited
+func (ipam *IpTree[T]) hasParentPrefix(r netaddr.IPRange, skip netaddr.IPPrefix) bool {
+	cover, err := CoveringPrefix(r)
+	if err != nil {
+		return false
+	}
+	parent := false
+	ipam.GetTree(familyOfIP(cover.IP())).WalkMatch(cover, func(n netaddr.IPPrefix, v interface{}) bool {
+		if d, ok := ipam.getData(v); ok && d.GetMeta().HasIpPrefix() && n != skip {
+			parent = true
+			return false
+		}
+		return true
+	})
+	return parent
+}
+
+// overlapping returns the prefixes and ranges in the tree that overlap with
+// the range r
+func (ipam *IpTree[T]) overlapping(r netaddr.IPRange) ([]netaddr.IPPrefix, []netaddr.IPRange) {
+	prefixes := []netaddr.IPPrefix{}
+	ipam.prefixesOverlapping(r, func(p netaddr.IPPrefix) bool {
+		prefixes = append(prefixes, p)
+		return true
+	})
+	return prefixes, ipam.overlappingRanges(r)
+}
+
+// overlappingRanges returns the ranges in the tree that overlap with the
+// range r
+func (ipam *IpTree[T]) overlappingRanges(r netaddr.IPRange) []netaddr.IPRange {
+	ranges := []netaddr.IPRange{}
+	ipam.rangesOverlapping(r, func(n *rangeNode[T]) bool {
+		ranges = append(ranges, n.r)
+		return true
+	})
+	return ranges
+}
+
+// prefixesOverlapping calls fn for the prefixes in the tree that overlap
+// with the range r until fn returns false. A prefix overlaps r when it
+// holds or is held by one of the at most 2*bits prefixes r consists of, so
+// the walk visits the parents of those prefixes and the prefixes inside r
+// and costs O(bits^2 + k) instead of walking every child of a covering
+// prefix
+func (ipam *IpTree[T]) prefixesOverlapping(r netaddr.IPRange, fn func(netaddr.IPPrefix) bool) {
+	parts, err := RangePrefixes(r)
+	if err != nil || len(parts) == 0 {
+		return
+	}
+	t := ipam.GetTree(familyOfIP(r.From()))
+	seen := map[netaddr.IPPrefix]struct{}{}
+	done := false
+	f := func(n netaddr.IPPrefix, v interface{}) bool {
+		d, ok := ipam.getData(v)
+		if !ok || !d.GetMeta().HasIpPrefix() {
+			return true
+		}
+		// a parent is found once for every part it holds
+		if _, ok := seen[n]; ok {
+			return true
+		}
+		seen[n] = struct{}{}
+		done = !fn(n)
+		return !done
+	}
+	for _, p := range parts {
+		if t.WalkMatch(p, f); done {
+			return
+		}
+		if t.WalkPrefix(p, f); done {
+			return
+		}
+	}
+}
+
+// rangeContains returns true if all addresses of range b are part of range a
+func rangeContains(a, b netaddr.IPRange) bool {
+	return !b.From().Less(a.From()) && !a.To().Less(b.To())
+}
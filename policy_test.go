@@ -0,0 +1,117 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestOverlapping(t *testing.T) {
+	ipam := New[string]()
+	for _, p := range []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.1.0/24", "10.1.2.0/24", "10.2.0.0/16", "11.0.0.0/8"} {
+		if err := ipam.AddPrefix(p, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ipam.AddRange("10.1.3.10-10.1.3.20", "r"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		r        string
+		prefixes []string
+		ranges   []string
+	}{
+		{"10.1.1.200-10.1.2.10", []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.1.0/24", "10.1.2.0/24"}, []string{}},
+		{"10.1.3.0-10.1.3.15", []string{"10.0.0.0/8", "10.1.0.0/16"}, []string{"10.1.3.10-10.1.3.20"}},
+		{"10.1.255.0-10.2.0.0", []string{"10.0.0.0/8", "10.1.0.0/16", "10.2.0.0/16"}, []string{}},
+		{"12.0.0.0-12.0.0.1", []string{}, []string{}},
+	}
+	for _, tt := range tests {
+		r := mustRange(t, tt.r)
+		prefixes, ranges := ipam.overlapping(r)
+		got := []string{}
+		for _, p := range prefixes {
+			got = append(got, p.String())
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.prefixes) {
+			t.Errorf("prefixes overlapping %s = %v, want %v", tt.r, got, tt.prefixes)
+		}
+		gotRanges := []string{}
+		for _, o := range ranges {
+			gotRanges = append(gotRanges, o.String())
+		}
+		if !reflect.DeepEqual(gotRanges, tt.ranges) {
+			t.Errorf("ranges overlapping %s = %v, want %v", tt.r, gotRanges, tt.ranges)
+		}
+	}
+}
+
+// addKey adds the prefix or range k to the tree
+func addKey(ipam *IpTree[string], k string) error {
+	if strings.Contains(k, "-") {
+		return ipam.AddRange(k, k)
+	}
+	return ipam.AddPrefix(k, k)
+}
+
+func TestPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		setup  []string
+		// add is the prefix or range that is added, del the prefix that
+		// is deleted
+		add, del string
+		err      error
+	}{
+		{"nested range", Policy{}, []string{"10.0.0.0/24", "10.0.0.10-10.0.0.20"}, "10.0.0.12-10.0.0.14", "", ErrNestedRange},
+		{"nested range allowed", Policy{AllowNestedRanges: true}, []string{"10.0.0.0/24", "10.0.0.10-10.0.0.20"}, "10.0.0.12-10.0.0.14", "", nil},
+		{"range around a range", Policy{}, []string{"10.0.0.0/24", "10.0.0.10-10.0.0.20"}, "10.0.0.5-10.0.0.30", "", ErrNestedRange},
+		{"range around a range allowed", Policy{AllowNestedRanges: true}, []string{"10.0.0.0/24", "10.0.0.10-10.0.0.20"}, "10.0.0.5-10.0.0.30", "", nil},
+		{"overlapping ranges", Policy{AllowNestedRanges: true}, []string{"10.0.0.0/24", "10.0.0.10-10.0.0.20"}, "10.0.0.15-10.0.0.30", "", ErrOverlap},
+		{"range without parent", Policy{}, []string{"10.0.0.0/24"}, "10.1.0.1-10.1.0.2", "", ErrNoParent},
+		{"range without parent allowed", Policy{AllowRangeWithoutParent: true}, []string{"10.0.0.0/24"}, "10.1.0.1-10.1.0.2", "", nil},
+		{"range over a prefix edge", Policy{AllowRangeWithoutParent: true}, []string{"10.0.0.0/24"}, "10.0.0.250-10.0.1.5", "", ErrOverlap},
+		{"prefix in a range", Policy{}, []string{"10.0.0.0/24", "10.0.0.10-10.0.0.20"}, "10.0.0.12/30", "", ErrInsideRange},
+		{"prefix in a range allowed", Policy{AllowPrefixInRange: true}, []string{"10.0.0.0/24", "10.0.0.10-10.0.0.20"}, "10.0.0.12/30", "", nil},
+		{"range around a prefix", Policy{}, []string{"10.0.0.0/24", "10.0.0.32/30"}, "10.0.0.30-10.0.0.40", "", ErrInsideRange},
+		{"range around a prefix allowed", Policy{AllowPrefixInRange: true}, []string{"10.0.0.0/24", "10.0.0.32/30"}, "10.0.0.30-10.0.0.40", "", nil},
+		{"prefix equal to a range", Policy{}, []string{"10.0.0.0/24", "10.0.0.32-10.0.0.35"}, "10.0.0.32/30", "", ErrInsideRange},
+		{"prefix equal to a range allowed", Policy{AllowPrefixEqualRange: true}, []string{"10.0.0.0/24", "10.0.0.32-10.0.0.35"}, "10.0.0.32/30", "", nil},
+		{"prefix equal to a range allowed inside", Policy{AllowPrefixInRange: true}, []string{"10.0.0.0/24", "10.0.0.32-10.0.0.35"}, "10.0.0.32/30", "", nil},
+		{"range equal to a prefix", Policy{}, []string{"10.0.0.0/24", "10.0.0.32/30"}, "10.0.0.32-10.0.0.35", "", ErrInsideRange},
+		{"range equal to a prefix allowed", Policy{AllowPrefixEqualRange: true}, []string{"10.0.0.32/30"}, "10.0.0.32-10.0.0.35", "", nil},
+		{"prefix in a range with equal allowed", Policy{AllowPrefixEqualRange: true}, []string{"10.0.0.0/24", "10.0.0.32-10.0.0.35"}, "10.0.0.32/31", "", ErrInsideRange},
+		{"prefix over a range edge", Policy{AllowPrefixInRange: true}, []string{"10.0.0.0/24", "10.0.0.10-10.0.0.20"}, "10.0.0.16/28", "", ErrOverlap},
+		{"delete the parent of a range", Policy{}, []string{"10.0.0.0/24", "10.0.0.10-10.0.0.20"}, "", "10.0.0.0/24", ErrNoParent},
+		{"delete the parent of a range allowed", Policy{AllowRangeWithoutParent: true}, []string{"10.0.0.0/24", "10.0.0.10-10.0.0.20"}, "", "10.0.0.0/24", nil},
+		{"delete a parent of a range with another parent", Policy{}, []string{"10.0.0.0/16", "10.0.0.0/24", "10.0.0.10-10.0.0.20"}, "", "10.0.0.0/24", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ipam := New[string](WithPolicy(tt.policy))
+			for _, k := range tt.setup {
+				if err := addKey(ipam, k); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.del != "" {
+				if err := ipam.DeletePrefix(tt.del); errors.Cause(err) != tt.err {
+					t.Errorf("delete %s returned %v, want %v", tt.del, err, tt.err)
+				}
+				return
+			}
+			ok, err := ipam.PreCheckAddition(tt.add)
+			if ok != (tt.err == nil) || ok && err != nil || !ok && errors.Cause(err) != tt.err {
+				t.Errorf("pre-check of %s returned %t %v, want %v", tt.add, ok, err, tt.err)
+			}
+			if err := addKey(ipam, tt.add); errors.Cause(err) != tt.err {
+				t.Errorf("add %s returned %v, want %v", tt.add, err, tt.err)
+			}
+		})
+	}
+}
@@ -47,7 +47,7 @@ func GetPrefixes(s string) (prefixes []netaddr.IPPrefix, err error) {
 		if p, err := netaddr.ParseIPPrefix(s); err != nil {
 			return nil, errors.Wrap(err, "cannot parse prefix")
 		} else {
-			prefixes = append(prefixes, p)
+			prefixes = append(prefixes, canonicalPrefix(p))
 		}
 	}
 	return prefixes, nil
@@ -68,6 +68,9 @@ func getPrefixesForRange(ra string) ([]netaddr.IPPrefix, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "error parsing ip-range")
 	}
+	if r, err = canonicalRange(r); err != nil {
+		return nil, err
+	}
 
 	// expand range to individual prefixes
 	var b netaddr.IPSetBuilder
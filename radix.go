@@ -0,0 +1,233 @@
+package main
+
+import (
+	"math/bits"
+
+	"inet.af/netaddr"
+)
+
+// radixBackend is a path compressed binary radix tree keyed by netaddr
+// prefixes, it does not convert to net.IPNet on any operation
+type radixBackend struct {
+	root *radixNode
+	size int
+}
+
+// radixNode is a node of the radix tree, a node without a value joins the
+// two subtrees that differ at bit bits
+type radixNode struct {
+	key    uint128
+	bits   uint8
+	prefix netaddr.IPPrefix
+	value  interface{}
+	set    bool
+	child  [2]*radixNode
+}
+
+// NewRadixBackend returns a backend on a radix tree
+func NewRadixBackend() Backend {
+	return &radixBackend{}
+}
+
+// radixKey returns the address of prefix p as the most significant bits of
+// a 128 bit integer
+func radixKey(ip netaddr.IP) uint128 {
+	if ip.Is4() {
+		return uint128{hi: uint64(ipv4Key(ip)) << 32}
+	}
+	return ipv6Key(ip)
+}
+
+// bit returns bit i of key k, bit 0 is the most significant bit
+func (k uint128) bit(i uint8) int {
+	if i < 64 {
+		return int(k.hi >> (63 - i) & 1)
+	}
+	return int(k.lo >> (127 - i) & 1)
+}
+
+// commonBits returns the amount of leading bits that a and b share
+func commonBits(a, b uint128) uint8 {
+	if x := a.hi ^ b.hi; x != 0 {
+		return uint8(bits.LeadingZeros64(x))
+	}
+	return uint8(64 + bits.LeadingZeros64(a.lo^b.lo))
+}
+
+// masked returns key k with the bits after the first n bits cleared
+func (k uint128) masked(n uint8) uint128 {
+	switch {
+	case n == 0:
+		return uint128{}
+	case n < 64:
+		return uint128{hi: k.hi >> (64 - n) << (64 - n)}
+	case n == 64:
+		return uint128{hi: k.hi}
+	case n < 128:
+		return uint128{hi: k.hi, lo: k.lo >> (128 - n) << (128 - n)}
+	}
+	return k
+}
+
+// holds returns true when node n is equal to or less specific than the
+// prefix with key k and length bits
+func (n *radixNode) holds(k uint128, bits uint8) bool {
+	return n.bits <= bits && commonBits(k, n.key) >= n.bits
+}
+
+func (b *radixBackend) Get(p netaddr.IPPrefix) (interface{}, bool) {
+	k, l := radixKey(p.IP()), p.Bits()
+	for n := b.root; n != nil && n.holds(k, l); n = n.child[k.bit(n.bits)] {
+		if n.bits == l {
+			return n.value, n.set
+		}
+	}
+	return nil, false
+}
+
+func (b *radixBackend) Add(p netaddr.IPPrefix, v interface{}) error {
+	p = p.Masked()
+	k, l := radixKey(p.IP()), p.Bits()
+	leaf := &radixNode{key: k, bits: l, prefix: p, value: v, set: true}
+	link := &b.root
+	for {
+		n := *link
+		if n == nil {
+			*link = leaf
+			b.size++
+			return nil
+		}
+		c := min(commonBits(k, n.key), l, n.bits)
+		switch {
+		case c == n.bits && c == l:
+			if !n.set {
+				b.size++
+			}
+			n.prefix, n.value, n.set = p, v, true
+			return nil
+		case c == n.bits:
+			link = &n.child[k.bit(n.bits)]
+			continue
+		case c == l:
+			// the new node holds the existing node
+			leaf.child[n.key.bit(l)] = n
+			*link = leaf
+		default:
+			glue := &radixNode{key: k.masked(c), bits: c}
+			glue.child[k.bit(c)] = leaf
+			glue.child[n.key.bit(c)] = n
+			*link = glue
+		}
+		b.size++
+		return nil
+	}
+}
+
+func (b *radixBackend) Delete(p netaddr.IPPrefix) (interface{}, bool) {
+	k, l := radixKey(p.IP()), p.Bits()
+	var parent **radixNode
+	link := &b.root
+	for n := *link; n != nil && n.holds(k, l); n = *link {
+		if n.bits == l {
+			if !n.set {
+				return nil, false
+			}
+			v := n.value
+			n.value, n.set = nil, false
+			b.size--
+			b.compact(link)
+			if parent != nil {
+				b.compact(parent)
+			}
+			return v, true
+		}
+		parent, link = link, &n.child[k.bit(n.bits)]
+	}
+	return nil, false
+}
+
+// compact removes the node at link when it has no value and less than two
+// children
+func (b *radixBackend) compact(link **radixNode) {
+	n := *link
+	if n == nil || n.set {
+		return
+	}
+	switch {
+	case n.child[0] == nil:
+		*link = n.child[1]
+	case n.child[1] == nil:
+		*link = n.child[0]
+	}
+}
+
+func (b *radixBackend) Match(ip netaddr.IP) (netaddr.IPPrefix, interface{}, bool) {
+	k, l := radixKey(ip), ip.BitLen()
+	var match *radixNode
+	for n := b.root; n != nil && n.holds(k, l); n = n.child[k.bit(n.bits)] {
+		if n.set {
+			match = n
+		}
+		if n.bits == l {
+			break
+		}
+	}
+	if match == nil {
+		return netaddr.IPPrefix{}, nil, false
+	}
+	return match.prefix, match.value, true
+}
+
+func (b *radixBackend) WalkMatch(p netaddr.IPPrefix, fn func(netaddr.IPPrefix, interface{}) bool) {
+	k, l := radixKey(p.IP()), p.Bits()
+	for n := b.root; n != nil && n.holds(k, l); n = n.child[k.bit(n.bits)] {
+		if n.set && !fn(n.prefix, n.value) {
+			return
+		}
+		if n.bits == l {
+			return
+		}
+	}
+}
+
+func (b *radixBackend) WalkPrefix(p netaddr.IPPrefix, fn func(netaddr.IPPrefix, interface{}) bool) {
+	k, l := radixKey(p.IP()), p.Bits()
+	n := b.root
+	for n != nil && n.bits < l {
+		if !n.holds(k, l) {
+			return
+		}
+		n = n.child[k.bit(n.bits)]
+	}
+	// the first node at or below the length of p holds the subtree of p
+	// when it starts with the bits of p
+	if n == nil || commonBits(k, n.key) < l {
+		return
+	}
+	walkRadix(n, fn)
+}
+
+func (b *radixBackend) Walk(fn func(netaddr.IPPrefix, interface{}) bool) {
+	walkRadix(b.root, fn)
+}
+
+// walkRadix calls fn for the nodes with a value below n in pre-order, it
+// returns false when the walk was stopped by fn
+func walkRadix(n *radixNode, fn func(netaddr.IPPrefix, interface{}) bool) bool {
+	if n == nil {
+		return true
+	}
+	if n.set && !fn(n.prefix, n.value) {
+		return false
+	}
+	return walkRadix(n.child[0], fn) && walkRadix(n.child[1], fn)
+}
+
+func (b *radixBackend) Size() int {
+	return b.size
+}
+
+func (b *radixBackend) Clear() {
+	b.root = nil
+	b.size = 0
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"inet.af/netaddr"
+)
+
+// rangeIndex holds the ranges of an address family in an interval tree, a
+// range is stored once in a node ordered by its first address and every
+// node knows the highest last address of its subtree, such that the ranges
+// overlapping an interval are found in O(log n + k). The tree is kept
+// balanced as an avl tree
+type rangeIndex[T any] struct {
+	root *rangeNode[T]
+	size int
+}
+
+// rangeNode is a range of the index together with its key and value
+type rangeNode[T any] struct {
+	r      netaddr.IPRange
+	key    string
+	value  T
+	maxTo  netaddr.IP
+	height int
+	left   *rangeNode[T]
+	right  *rangeNode[T]
+}
+
+func newRangeIndex[T any]() *rangeIndex[T] {
+	return &rangeIndex[T]{}
+}
+
+// newRangeIndexes returns an empty range index for every address family
+func newRangeIndexes[T any]() map[Family]*rangeIndex[T] {
+	x := map[Family]*rangeIndex[T]{}
+	for _, f := range Families {
+		x[f] = newRangeIndex[T]()
+	}
+	return x
+}
+
+// compareRange orders ranges by their first address and ranges with the
+// same first address by their last address
+func compareRange(a, b netaddr.IPRange) int {
+	switch {
+	case a.From().Less(b.From()):
+		return -1
+	case b.From().Less(a.From()):
+		return 1
+	case a.To().Less(b.To()):
+		return -1
+	case b.To().Less(a.To()):
+		return 1
+	}
+	return 0
+}
+
+// get returns the node of the canonical range r
+func (x *rangeIndex[T]) get(r netaddr.IPRange) (*rangeNode[T], bool) {
+	n := x.root
+	for n != nil {
+		switch c := compareRange(r, n.r); {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			n = n.right
+		default:
+			return n, true
+		}
+	}
+	return nil, false
+}
+
+// insert stores range r with key and value, the value of an existing range
+// is replaced and returned
+func (x *rangeIndex[T]) insert(r netaddr.IPRange, key string, value T) (T, bool) {
+	var old T
+	root, existing := x.root.insert(&rangeNode[T]{r: r, key: key, value: value, maxTo: r.To(), height: 1})
+	x.root = root
+	if existing != nil {
+		old, existing.value = existing.value, value
+		return old, true
+	}
+	x.size++
+	return old, false
+}
+
+// delete removes range r and returns its node
+func (x *rangeIndex[T]) delete(r netaddr.IPRange) (*rangeNode[T], bool) {
+	root, removed := x.root.delete(r)
+	x.root = root
+	if removed == nil {
+		return nil, false
+	}
+	x.size--
+	return removed, true
+}
+
+// overlapping calls fn for the ranges that overlap with range r in address
+// order, the walk stops when fn returns false
+func (x *rangeIndex[T]) overlapping(r netaddr.IPRange, fn func(*rangeNode[T]) bool) {
+	x.root.walkOverlap(r, fn)
+}
+
+// walk calls fn for all ranges in address order, the walk stops when fn
+// returns false
+func (x *rangeIndex[T]) walk(fn func(*rangeNode[T]) bool) {
+	x.root.walk(fn)
+}
+
+func (x *rangeIndex[T]) clear() {
+	x.root = nil
+	x.size = 0
+}
+
+// insert adds node m to the subtree of n, the node of an equal range is
+// returned instead of adding m
+func (n *rangeNode[T]) insert(m *rangeNode[T]) (*rangeNode[T], *rangeNode[T]) {
+	if n == nil {
+		return m, nil
+	}
+	var existing *rangeNode[T]
+	switch c := compareRange(m.r, n.r); {
+	case c < 0:
+		n.left, existing = n.left.insert(m)
+	case c > 0:
+		n.right, existing = n.right.insert(m)
+	default:
+		return n, n
+	}
+	return n.balance(), existing
+}
+
+// delete removes the node of range r from the subtree of n
+func (n *rangeNode[T]) delete(r netaddr.IPRange) (*rangeNode[T], *rangeNode[T]) {
+	if n == nil {
+		return nil, nil
+	}
+	var removed *rangeNode[T]
+	switch c := compareRange(r, n.r); {
+	case c < 0:
+		n.left, removed = n.left.delete(r)
+	case c > 0:
+		n.right, removed = n.right.delete(r)
+	default:
+		if n.left == nil {
+			return n.right, n
+		}
+		if n.right == nil {
+			return n.left, n
+		}
+		// the first node of the right subtree takes the place of n
+		m := n.right
+		for m.left != nil {
+			m = m.left
+		}
+		m.right, _ = n.right.delete(m.r)
+		m.left = n.left
+		return m.balance(), n
+	}
+	if removed == nil {
+		return n, nil
+	}
+	return n.balance(), removed
+}
+
+func (n *rangeNode[T]) walkOverlap(r netaddr.IPRange, fn func(*rangeNode[T]) bool) bool {
+	// no range of the subtree reaches the first address of r
+	if n == nil || n.maxTo.Less(r.From()) {
+		return true
+	}
+	if !n.left.walkOverlap(r, fn) {
+		return false
+	}
+	// n and the ranges of the right subtree start after r
+	if r.To().Less(n.r.From()) {
+		return true
+	}
+	if !n.r.To().Less(r.From()) && !fn(n) {
+		return false
+	}
+	return n.right.walkOverlap(r, fn)
+}
+
+func (n *rangeNode[T]) walk(fn func(*rangeNode[T]) bool) bool {
+	if n == nil {
+		return true
+	}
+	return n.left.walk(fn) && fn(n) && n.right.walk(fn)
+}
+
+func (n *rangeNode[T]) getHeight() int {
+	if n == nil {
+		return 0
+	}
+	return n.height
+}
+
+// update recomputes the height and the highest last address of n from its
+// children
+func (n *rangeNode[T]) update() {
+	n.height = 1 + max(n.left.getHeight(), n.right.getHeight())
+	n.maxTo = n.r.To()
+	if n.left != nil && n.maxTo.Less(n.left.maxTo) {
+		n.maxTo = n.left.maxTo
+	}
+	if n.right != nil && n.maxTo.Less(n.right.maxTo) {
+		n.maxTo = n.right.maxTo
+	}
+}
+
+func (n *rangeNode[T]) rotateLeft() *rangeNode[T] {
+	r := n.right
+	n.right, r.left = r.left, n
+	n.update()
+	r.update()
+	return r
+}
+
+func (n *rangeNode[T]) rotateRight() *rangeNode[T] {
+	l := n.left
+	n.left, l.right = l.right, n
+	n.update()
+	l.update()
+	return l
+}
+
+// balance restores the height difference of the subtrees of n to at most
+// one and returns the root of the subtree
+func (n *rangeNode[T]) balance() *rangeNode[T] {
+	n.update()
+	switch d := n.left.getHeight() - n.right.getHeight(); {
+	case d > 1:
+		if n.left.left.getHeight() < n.left.right.getHeight() {
+			n.left = n.left.rotateLeft()
+		}
+		return n.rotateRight()
+	case d < -1:
+		if n.right.right.getHeight() < n.right.left.getHeight() {
+			n.right = n.right.rotateRight()
+		}
+		return n.rotateLeft()
+	}
+	return n
+}
+
+// rangeIndex returns the index of the ranges of address family f
+func (ipam *IpTree[T]) rangeIndex(f Family) *rangeIndex[T] {
+	return ipam.ranges[f]
+}
+
+// rangesOverlapping calls fn for the ranges in the tree that overlap with
+// range r, the walk stops when fn returns false
+func (ipam *IpTree[T]) rangesOverlapping(r netaddr.IPRange, fn func(*rangeNode[T]) bool) {
+	ipam.rangeIndex(familyOfIP(r.From())).overlapping(r, fn)
+}
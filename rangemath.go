@@ -0,0 +1,122 @@
+package main
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+	"inet.af/netaddr"
+)
+
+// CoveringPrefix returns the smallest prefix that covers all addresses of
+// the range r
+func CoveringPrefix(r netaddr.IPRange) (netaddr.IPPrefix, error) {
+	r, err := canonicalRange(r)
+	if err != nil {
+		return netaddr.IPPrefix{}, err
+	}
+	// start from the host prefix of the first address and widen the mask
+	// until the last address is included
+	for bits := int(r.From().BitLen()); bits >= 0; bits-- {
+		p, err := r.From().Prefix(uint8(bits))
+		if err != nil {
+			return netaddr.IPPrefix{}, errors.Wrap(err, "cannot get prefix")
+		}
+		if p.Contains(r.To()) {
+			return p, nil
+		}
+	}
+	return netaddr.IPPrefix{}, errors.Errorf("no covering prefix for range %s", r.String())
+}
+
+// AggregatePrefixFromRange returns the smallest prefix that covers the
+// range r
+func AggregatePrefixFromRange(r *netaddr.IPRange) (*netaddr.IPPrefix, error) {
+	if r == nil {
+		return nil, errors.New("IP range is nil")
+	}
+	p, err := CoveringPrefix(*r)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// RangePrefixes returns the exact cidr decomposition of the range r
+func RangePrefixes(r netaddr.IPRange) ([]netaddr.IPPrefix, error) {
+	r, err := canonicalRange(r)
+	if err != nil {
+		return nil, err
+	}
+	return r.Prefixes(), nil
+}
+
+// PrefixRange returns the range of addresses of the prefix p
+func PrefixRange(p netaddr.IPPrefix) netaddr.IPRange {
+	return canonicalPrefix(p).Range()
+}
+
+// RangeIntersect returns the addresses that are part of both ranges, false
+// is returned when the ranges do not overlap
+func RangeIntersect(a, b netaddr.IPRange) (netaddr.IPRange, bool) {
+	if !a.Overlaps(b) {
+		return netaddr.IPRange{}, false
+	}
+	from := a.From()
+	if from.Less(b.From()) {
+		from = b.From()
+	}
+	to := a.To()
+	if b.To().Less(to) {
+		to = b.To()
+	}
+	return netaddr.IPRangeFrom(from, to), true
+}
+
+// RangeUnion returns the union of both ranges, overlapping or adjacent
+// ranges are merged into a single range
+func RangeUnion(a, b netaddr.IPRange) []netaddr.IPRange {
+	if b.From().Less(a.From()) {
+		a, b = b, a
+	}
+	if familyOfIP(a.From()) != familyOfIP(b.From()) {
+		return []netaddr.IPRange{a, b}
+	}
+	if a.Overlaps(b) || a.To().Next() == b.From() {
+		to := a.To()
+		if to.Less(b.To()) {
+			to = b.To()
+		}
+		return []netaddr.IPRange{netaddr.IPRangeFrom(a.From(), to)}
+	}
+	return []netaddr.IPRange{a, b}
+}
+
+// RangeSubtract returns the addresses of range a that are not part of
+// range b, the result holds 0, 1 or 2 ranges
+func RangeSubtract(a, b netaddr.IPRange) []netaddr.IPRange {
+	if !a.Overlaps(b) {
+		return []netaddr.IPRange{a}
+	}
+	result := []netaddr.IPRange{}
+	if a.From().Less(b.From()) {
+		result = append(result, netaddr.IPRangeFrom(a.From(), b.From().Prior()))
+	}
+	if b.To().Less(a.To()) {
+		result = append(result, netaddr.IPRangeFrom(b.To().Next(), a.To()))
+	}
+	return result
+}
+
+// AddressCount returns the amount of addresses in the range r
+func AddressCount(r netaddr.IPRange) *big.Int {
+	from := r.From().As16()
+	to := r.To().As16()
+	count := new(big.Int).Sub(new(big.Int).SetBytes(to[:]), new(big.Int).SetBytes(from[:]))
+	return count.Add(count, big.NewInt(1))
+}
+
+// PrefixAddressCount returns the amount of addresses in the prefix p
+func PrefixAddressCount(p netaddr.IPPrefix) *big.Int {
+	p = canonicalPrefix(p)
+	return new(big.Int).Lsh(big.NewInt(1), uint(p.IP().BitLen()-p.Bits()))
+}
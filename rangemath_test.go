@@ -0,0 +1,213 @@
+package main
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"inet.af/netaddr"
+)
+
+func mustRange(t *testing.T, s string) netaddr.IPRange {
+	t.Helper()
+	r, err := netaddr.ParseIPRange(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func mustPrefixes(t *testing.T, ss ...string) []netaddr.IPPrefix {
+	t.Helper()
+	prefixes := []netaddr.IPPrefix{}
+	for _, s := range ss {
+		prefixes = append(prefixes, netaddr.MustParseIPPrefix(s))
+	}
+	return prefixes
+}
+
+func mustRanges(t *testing.T, ss ...string) []netaddr.IPRange {
+	t.Helper()
+	ranges := []netaddr.IPRange{}
+	for _, s := range ss {
+		ranges = append(ranges, mustRange(t, s))
+	}
+	return ranges
+}
+
+func TestCoveringPrefix(t *testing.T) {
+	tests := []struct {
+		r    string
+		want string
+	}{
+		{"0.0.0.0-255.255.255.255", "0.0.0.0/0"},
+		{"::-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "::/0"},
+		{"10.0.0.1-10.0.0.1", "10.0.0.1/32"},
+		{"2001:db8::1-2001:db8::1", "2001:db8::1/128"},
+		{"10.0.0.0-10.0.0.255", "10.0.0.0/24"},
+		{"10.0.0.5-10.0.0.9", "10.0.0.0/28"},
+		{"10.0.0.255-10.0.1.0", "10.0.0.0/23"},
+		{"127.255.255.255-128.0.0.0", "0.0.0.0/0"},
+		{"dead:beaf::f-dead:beaf::ffff", "dead:beaf::/112"},
+		{"::ffff:10.0.0.0-::ffff:10.0.0.3", "10.0.0.0/30"},
+	}
+	for _, tt := range tests {
+		got, err := CoveringPrefix(mustRange(t, tt.r))
+		if err != nil {
+			t.Errorf("CoveringPrefix(%s): %v", tt.r, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("CoveringPrefix(%s) = %s, want %s", tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestRangePrefixes(t *testing.T) {
+	tests := []struct {
+		r    string
+		want []string
+	}{
+		{"0.0.0.0-255.255.255.255", []string{"0.0.0.0/0"}},
+		{"::-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", []string{"::/0"}},
+		{"10.0.0.1-10.0.0.1", []string{"10.0.0.1/32"}},
+		{"10.0.0.1-10.0.0.6", []string{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6/32"}},
+		{"10.0.0.255-10.0.1.0", []string{"10.0.0.255/32", "10.0.1.0/32"}},
+		{"2001:db8::-2001:db8::2", []string{"2001:db8::/127", "2001:db8::2/128"}},
+	}
+	for _, tt := range tests {
+		got, err := RangePrefixes(mustRange(t, tt.r))
+		if err != nil {
+			t.Errorf("RangePrefixes(%s): %v", tt.r, err)
+			continue
+		}
+		if want := mustPrefixes(t, tt.want...); !reflect.DeepEqual(got, want) {
+			t.Errorf("RangePrefixes(%s) = %v, want %v", tt.r, got, want)
+		}
+	}
+}
+
+func TestRangeMathRejectsMixedFamilies(t *testing.T) {
+	r := netaddr.IPRangeFrom(netaddr.MustParseIP("10.0.0.1"), netaddr.MustParseIP("2001:db8::1"))
+	if _, err := CoveringPrefix(r); err == nil {
+		t.Error("CoveringPrefix accepted a range of mixed families")
+	}
+	if _, err := RangePrefixes(r); err == nil {
+		t.Error("RangePrefixes accepted a range of mixed families")
+	}
+}
+
+func TestRangeIntersect(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"10.0.0.0-10.0.0.10", "10.0.0.5-10.0.0.20", "10.0.0.5-10.0.0.10"},
+		{"10.0.0.0-10.0.0.10", "10.0.0.10-10.0.0.20", "10.0.0.10-10.0.0.10"},
+		{"10.0.0.0-10.0.0.10", "10.0.0.11-10.0.0.20", ""},
+		{"0.0.0.0-255.255.255.255", "10.0.0.3-10.0.0.7", "10.0.0.3-10.0.0.7"},
+		{"::-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "2001:db8::1-2001:db8::1", "2001:db8::1-2001:db8::1"},
+		{"10.0.0.0-10.0.0.10", "2001:db8::-2001:db8::10", ""},
+	}
+	for _, tt := range tests {
+		got, ok := RangeIntersect(mustRange(t, tt.a), mustRange(t, tt.b))
+		if tt.want == "" {
+			if ok {
+				t.Errorf("RangeIntersect(%s, %s) = %s, want none", tt.a, tt.b, got)
+			}
+			continue
+		}
+		if !ok || got != mustRange(t, tt.want) {
+			t.Errorf("RangeIntersect(%s, %s) = %s %t, want %s", tt.a, tt.b, got, ok, tt.want)
+		}
+	}
+}
+
+func TestRangeUnion(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want []string
+	}{
+		{"10.0.0.0-10.0.0.10", "10.0.0.5-10.0.0.20", []string{"10.0.0.0-10.0.0.20"}},
+		{"10.0.0.11-10.0.0.20", "10.0.0.0-10.0.0.10", []string{"10.0.0.0-10.0.0.20"}},
+		{"10.0.0.0-10.0.0.10", "10.0.0.12-10.0.0.20", []string{"10.0.0.0-10.0.0.10", "10.0.0.12-10.0.0.20"}},
+		{"0.0.0.0-255.255.255.255", "10.0.0.3-10.0.0.7", []string{"0.0.0.0-255.255.255.255"}},
+		{"::-::ffff", "::1:0-::1:ffff", []string{"::-::1:ffff"}},
+		{"10.0.0.0-10.0.0.10", "::-::10", []string{"10.0.0.0-10.0.0.10", "::-::10"}},
+	}
+	for _, tt := range tests {
+		got := RangeUnion(mustRange(t, tt.a), mustRange(t, tt.b))
+		if want := mustRanges(t, tt.want...); !sameRanges(got, want) {
+			t.Errorf("RangeUnion(%s, %s) = %v, want %v", tt.a, tt.b, got, want)
+		}
+	}
+}
+
+func TestRangeSubtract(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want []string
+	}{
+		{"10.0.0.0-10.0.0.20", "10.0.0.5-10.0.0.9", []string{"10.0.0.0-10.0.0.4", "10.0.0.10-10.0.0.20"}},
+		{"10.0.0.0-10.0.0.20", "10.0.0.0-10.0.0.9", []string{"10.0.0.10-10.0.0.20"}},
+		{"10.0.0.0-10.0.0.20", "10.0.0.15-10.0.0.30", []string{"10.0.0.0-10.0.0.14"}},
+		{"10.0.0.0-10.0.0.20", "10.0.0.21-10.0.0.30", []string{"10.0.0.0-10.0.0.20"}},
+		{"10.0.0.5-10.0.0.5", "0.0.0.0-255.255.255.255", []string{}},
+		{"0.0.0.0-255.255.255.255", "0.0.0.0-0.0.0.0", []string{"0.0.0.1-255.255.255.255"}},
+		{"::-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", []string{"::-ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe"}},
+	}
+	for _, tt := range tests {
+		got := RangeSubtract(mustRange(t, tt.a), mustRange(t, tt.b))
+		if want := mustRanges(t, tt.want...); !sameRanges(got, want) {
+			t.Errorf("RangeSubtract(%s, %s) = %v, want %v", tt.a, tt.b, got, want)
+		}
+	}
+}
+
+func TestAddressCount(t *testing.T) {
+	tests := []struct {
+		r    string
+		want string
+	}{
+		{"10.0.0.1-10.0.0.1", "1"},
+		{"10.0.0.5-10.0.0.9", "5"},
+		{"0.0.0.0-255.255.255.255", "4294967296"},
+		{"::-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "340282366920938463463374607431768211456"},
+		{"dead:beaf::f-dead:beaf::ffff", "65521"},
+	}
+	for _, tt := range tests {
+		want, _ := new(big.Int).SetString(tt.want, 10)
+		if got := AddressCount(mustRange(t, tt.r)); got.Cmp(want) != 0 {
+			t.Errorf("AddressCount(%s) = %s, want %s", tt.r, got, want)
+		}
+	}
+
+	prefixes := []struct {
+		p    string
+		want string
+	}{
+		{"0.0.0.0/0", "4294967296"},
+		{"::/0", "340282366920938463463374607431768211456"},
+		{"10.0.0.1/32", "1"},
+		{"2001:db8::/64", "18446744073709551616"},
+	}
+	for _, tt := range prefixes {
+		want, _ := new(big.Int).SetString(tt.want, 10)
+		if got := PrefixAddressCount(netaddr.MustParseIPPrefix(tt.p)); got.Cmp(want) != 0 {
+			t.Errorf("PrefixAddressCount(%s) = %s, want %s", tt.p, got, want)
+		}
+	}
+}
+
+// sameRanges compares range slices, an empty and a nil slice are equal
+func sameRanges(a, b []netaddr.IPRange) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ErrRevisionConflict is returned when an entry changed since the revision
+// the caller expected
+var ErrRevisionConflict = errors.New("revision conflict")
+
+// EntryRevision returns the revision of the tree at the last change of the
+// prefix, range or ip address with key k
+func (ipam *IpTree[T]) EntryRevision(k string) (uint64, bool) {
+	if ipam.events == nil {
+		return 0, false
+	}
+	k, _, err := Canonicalize(k)
+	if err != nil {
+		return 0, false
+	}
+	ipam.events.m.Lock()
+	defer ipam.events.m.Unlock()
+	rev, ok := ipam.events.entries[k]
+	return rev, ok
+}
+
+// checkRevision validates that the entry with key k is still at revision
+// rev, a revision of 0 expects the entry to not exist
+func (ipam *IpTree[T]) checkRevision(k string, rev uint64) error {
+	current, ok := ipam.EntryRevision(k)
+	switch {
+	case rev == 0 && ok:
+		return errors.Wrapf(ErrRevisionConflict, "%s exists at revision %d", k, current)
+	case rev != 0 && !ok:
+		return errors.Wrapf(ErrRevisionConflict, "%s does not exist, expected revision %d", k, rev)
+	case rev != current:
+		return errors.Wrapf(ErrRevisionConflict, "%s is at revision %d, expected revision %d", k, current, rev)
+	}
+	return nil
+}
+
+// atRevision runs change when the entry with key k is still at revision
+// rev. The check and the change hold the commit lock of the tree, two
+// AtRevision calls for the same revision cannot both pass the check. The
+// other methods that change the tree do not take the lock, a caller that
+// mixes them with the AtRevision methods serializes all changes itself
+func (ipam *IpTree[T]) atRevision(k string, rev uint64, change func() error) error {
+	if ipam.events != nil {
+		ipam.events.commit.Lock()
+		defer ipam.events.commit.Unlock()
+	}
+	if err := ipam.checkRevision(k, rev); err != nil {
+		return err
+	}
+	return change()
+}
+
+// AddPrefixAtRevision adds the prefix p when it does not exist and rev is 0
+// or updates its value when the prefix did not change since revision rev.
+// The check and the change are atomic against the other AtRevision methods
+func (ipam *IpTree[T]) AddPrefixAtRevision(p string, value T, rev uint64) error {
+	p, _, err := ipam.tree(p)
+	if err != nil {
+		return err
+	}
+	return ipam.atRevision(p, rev, func() error {
+		return ipam.AddPrefix(p, value)
+	})
+}
+
+// AddRangeAtRevision adds the range ra when it does not exist and rev is 0
+// or updates its value when the range did not change since revision rev.
+// The check and the change are atomic against the other AtRevision methods
+func (ipam *IpTree[T]) AddRangeAtRevision(ra string, value T, rev uint64) error {
+	ra, _, err := ipam.tree(ra)
+	if err != nil {
+		return err
+	}
+	return ipam.atRevision(ra, rev, func() error {
+		if rev == 0 {
+			return ipam.AddRange(ra, value)
+		}
+		return ipam.updateRange(ra, value)
+	})
+}
+
+// updateRange replaces the value of an existing range
+func (ipam *IpTree[T]) updateRange(ra string, value T) error {
+	r, err := ParseRange(ra)
+	if err != nil {
+		return err
+	}
+	old, ok := ipam.GetIPRange(r)
+	if !ok {
+		return errors.Wrapf(ErrNotFound, "range %s", ra)
+	}
+	ipam.rangeIndex(familyOfIP(r.From())).insert(r, ra, value)
+	return ipam.emit(EventUpdated, ra, old, value)
+}
+
+// DeletePrefixAtRevision deletes the prefix p with the delete options o when
+// the prefix did not change since revision rev. The check and the change
+// are atomic against the other AtRevision methods
+func (ipam *IpTree[T]) DeletePrefixAtRevision(p string, rev uint64, o DeleteOptions) (*DeleteResult, error) {
+	p, _, err := ipam.tree(p)
+	if err != nil {
+		return nil, err
+	}
+	if rev == 0 {
+		return nil, errors.Wrapf(ErrRevisionConflict, "%s cannot be deleted at revision 0", p)
+	}
+	var result *DeleteResult
+	err = ipam.atRevision(p, rev, func() error {
+		result, err = ipam.DeletePrefixWithOptions(p, o)
+		return err
+	})
+	return result, err
+}
+
+// DeleteRangeAtRevision deletes the range ra when the range did not change
+// since revision rev. The check and the change are atomic against the
+// other AtRevision methods
+func (ipam *IpTree[T]) DeleteRangeAtRevision(ra string, rev uint64) error {
+	ra, _, err := ipam.tree(ra)
+	if err != nil {
+		return err
+	}
+	if rev == 0 {
+		return errors.Wrapf(ErrRevisionConflict, "%s cannot be deleted at revision 0", ra)
+	}
+	return ipam.atRevision(ra, rev, func() error {
+		return ipam.DeleteRange(ra)
+	})
+}
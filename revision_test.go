@@ -0,0 +1,107 @@
+package main
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestAtRevision(t *testing.T) {
+	ipam := New[string]()
+	if err := ipam.AddPrefixAtRevision("10.0.0.0/24", "net", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := ipam.AddRangeAtRevision("10.0.0.10-10.0.0.20", "pool", 0); err != nil {
+		t.Fatal(err)
+	}
+	prefixRev, _ := ipam.EntryRevision("10.0.0.0/24")
+	rangeRev, _ := ipam.EntryRevision("10.0.0.10-10.0.0.20")
+
+	tests := []struct {
+		name string
+		fn   func() error
+		err  error
+	}{
+		{"add an existing prefix at revision 0", func() error {
+			return ipam.AddPrefixAtRevision("10.0.0.0/24", "x", 0)
+		}, ErrRevisionConflict},
+		{"add an existing range at revision 0", func() error {
+			return ipam.AddRangeAtRevision("10.0.0.10-10.0.0.20", "x", 0)
+		}, ErrRevisionConflict},
+		{"update a missing prefix", func() error {
+			return ipam.AddPrefixAtRevision("10.0.1.0/24", "x", prefixRev)
+		}, ErrRevisionConflict},
+		{"delete a prefix at revision 0", func() error {
+			_, err := ipam.DeletePrefixAtRevision("10.0.0.0/24", 0, DeleteOptions{})
+			return err
+		}, ErrRevisionConflict},
+		{"delete a range at revision 0", func() error {
+			return ipam.DeleteRangeAtRevision("10.0.0.10-10.0.0.20", 0)
+		}, ErrRevisionConflict},
+		{"update the prefix", func() error {
+			return ipam.AddPrefixAtRevision("10.0.0.0/24", "net2", prefixRev)
+		}, nil},
+		{"update the prefix at a stale revision", func() error {
+			return ipam.AddPrefixAtRevision("10.0.0.0/24", "net3", prefixRev)
+		}, ErrRevisionConflict},
+		{"update the range", func() error {
+			return ipam.AddRangeAtRevision("10.0.0.10-10.0.0.20", "pool2", rangeRev)
+		}, nil},
+		{"delete the range at a stale revision", func() error {
+			return ipam.DeleteRangeAtRevision("10.0.0.10-10.0.0.20", rangeRev)
+		}, ErrRevisionConflict},
+	}
+	for _, tt := range tests {
+		if err := tt.fn(); errors.Cause(err) != tt.err {
+			t.Errorf("%s returned %v, want %v", tt.name, err, tt.err)
+		}
+	}
+	if v, _ := ipam.GetPrefix("10.0.0.0/24"); v != "net2" {
+		t.Errorf("prefix has value %q, want net2", v)
+	}
+	if v, _ := ipam.GetRange("10.0.0.10-10.0.0.20"); v != "pool2" {
+		t.Errorf("range has value %q, want pool2", v)
+	}
+
+	rangeRev, _ = ipam.EntryRevision("10.0.0.10-10.0.0.20")
+	if err := ipam.DeleteRangeAtRevision("10.0.0.10-10.0.0.20", rangeRev); err != nil {
+		t.Fatal(err)
+	}
+	prefixRev, _ = ipam.EntryRevision("10.0.0.0/24")
+	if _, err := ipam.DeletePrefixAtRevision("10.0.0.0/24", prefixRev, DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(ipam.Export()); n != 0 {
+		t.Errorf("tree holds %d entries", n)
+	}
+}
+
+// concurrent writers of the same revision cannot both pass the check
+func TestAtRevisionConcurrent(t *testing.T) {
+	ipam := New[string]()
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- ipam.AddPrefixAtRevision("10.0.0.0/24", "net", 0)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	added := 0
+	for err := range errs {
+		switch errors.Cause(err) {
+		case nil:
+			added++
+		case ErrRevisionConflict:
+		default:
+			t.Error(err)
+		}
+	}
+	if added != 1 {
+		t.Errorf("%d writers added the prefix, want 1", added)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"inet.af/netaddr"
+)
+
+// Snapshot is the content of the tree at a revision
+type Snapshot[T any] struct {
+	Revision uint64
+	Time     time.Time
+	entries  map[string]Entry[T]
+}
+
+// Change is a difference of an entry between two snapshots, the type is
+// added, deleted or updated
+type Change[T any] struct {
+	Type   EventType
+	Key    string
+	Before T
+	After  T
+}
+
+// Entries returns the entries of the snapshot sorted by key
+func (s *Snapshot[T]) Entries() []Entry[T] {
+	entries := make([]Entry[T], 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+// Get returns the entry with key k of the snapshot
+func (s *Snapshot[T]) Get(k string) (Entry[T], bool) {
+	k, _, err := Canonicalize(k)
+	if err != nil {
+		return Entry[T]{}, false
+	}
+	e, ok := s.entries[k]
+	return e, ok
+}
+
+// Prefix returns the entries of the snapshot that overlap with prefix p
+// sorted by key
+func (s *Snapshot[T]) Prefix(p string) ([]Entry[T], error) {
+	p, _, err := Canonicalize(p)
+	if err != nil {
+		return nil, err
+	}
+	pfx, err := netaddr.ParseIPPrefix(p)
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalid, "cannot parse prefix %s: %v", p, err)
+	}
+	entries := []Entry[T]{}
+	for _, e := range s.Entries() {
+		if r, err := keyRange(e.Key); err == nil && r.Overlaps(pfx.Range()) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// Snapshot returns the content of the tree at the current revision
+func (ipam *IpTree[T]) Snapshot() *Snapshot[T] {
+	s := &Snapshot[T]{
+		Revision: ipam.Revision(),
+		Time:     ipam.now().UTC(),
+		entries:  map[string]Entry[T]{},
+	}
+	for _, e := range ipam.Export() {
+		s.entries[e.Key] = e
+	}
+	return s
+}
+
+// SnapshotAt returns the content of the tree at revision rev, the changes
+// after rev are reverted from the event history. The history holds the
+// last 1000 changes unless WithEventHistory sets another size, so a
+// snapshot can go back that many revisions. ErrCompacted is returned for an
+// older revision, it names the oldest revision that can be restored
+func (ipam *IpTree[T]) SnapshotAt(rev uint64) (*Snapshot[T], error) {
+	s := ipam.Snapshot()
+	if ipam.events == nil {
+		return nil, errors.New("tree has no history")
+	}
+	l := ipam.events
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	switch {
+	case rev > l.revision:
+		return nil, errors.Wrapf(ErrNotFound, "revision %d is after revision %d", rev, l.revision)
+	case rev == l.revision:
+		return s, nil
+	case len(l.history) == 0 || l.history[0].Revision > rev+1:
+		return nil, errors.Wrapf(ErrCompacted, "revision %d is older than the event history, the oldest revision is %d", rev, l.oldest())
+	}
+	s.Revision = rev
+	s.Time = time.Time{}
+	for i := len(l.history) - 1; i >= 0; i-- {
+		e := l.history[i]
+		if e.Revision <= rev {
+			break
+		}
+		switch e.Type {
+		case EventAdded, EventAllocated:
+			delete(s.entries, e.Key)
+		default:
+			// the revision of a restored entry is set from the history below
+			s.entries[e.Key] = Entry[T]{Key: e.Key, Value: e.OldValue}
+		}
+	}
+	for _, e := range l.history {
+		if e.Revision > rev {
+			break
+		}
+		if se, ok := s.entries[e.Key]; ok {
+			se.Revision = e.Revision
+			s.entries[e.Key] = se
+		}
+		if e.Revision == rev {
+			s.Time = e.Time
+		}
+	}
+	return s, nil
+}
+
+// SnapshotAtTime returns the content of the tree at time t, like SnapshotAt
+// it is limited to the time span of the event history
+func (ipam *IpTree[T]) SnapshotAtTime(t time.Time) (*Snapshot[T], error) {
+	if ipam.events == nil {
+		return nil, errors.New("tree has no history")
+	}
+	l := ipam.events
+	l.m.Lock()
+	rev := l.revision
+	compacted := false
+	for i := len(l.history) - 1; i >= 0; i-- {
+		if !l.history[i].Time.After(t) {
+			break
+		}
+		rev = l.history[i].Revision - 1
+		compacted = i == 0 && rev > 0
+	}
+	l.m.Unlock()
+	if compacted {
+		return nil, errors.Wrapf(ErrCompacted, "time %s is before the event history", t)
+	}
+	s, err := ipam.SnapshotAt(rev)
+	if err != nil {
+		return nil, err
+	}
+	s.Time = t
+	return s, nil
+}
+
+// Diff returns the changes between snapshot a and snapshot b sorted by key,
+// an entry is updated when its value changed
+func Diff[T any](a, b *Snapshot[T]) []Change[T] {
+	changes := []Change[T]{}
+	for k, ea := range a.entries {
+		eb, ok := b.entries[k]
+		switch {
+		case !ok:
+			changes = append(changes, Change[T]{Type: EventDeleted, Key: k, Before: ea.Value})
+		case !reflect.DeepEqual(ea.Value, eb.Value):
+			changes = append(changes, Change[T]{Type: EventUpdated, Key: k, Before: ea.Value, After: eb.Value})
+		}
+	}
+	for k, eb := range b.entries {
+		if _, ok := a.entries[k]; !ok {
+			changes = append(changes, Change[T]{Type: EventAdded, Key: k, After: eb.Value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// DiffRevisions returns the changes of the tree between revision a and
+// revision b
+func (ipam *IpTree[T]) DiffRevisions(a, b uint64) ([]Change[T], error) {
+	sa, err := ipam.SnapshotAt(a)
+	if err != nil {
+		return nil, err
+	}
+	sb, err := ipam.SnapshotAt(b)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(sa, sb), nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestSnapshotAtHistoryLimit(t *testing.T) {
+	ipam := New[string](WithEventHistory(10))
+	if err := ipam.AddPrefix("10.0.0.0/16", "net"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 20; i++ {
+		if err := ipam.ClaimIP(fmt.Sprintf("10.0.0.%d", i), "host"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// revision 21 is current, the history holds revisions 12 to 21
+	tests := []struct {
+		rev     uint64
+		err     error
+		entries int
+	}{
+		{21, nil, 21},
+		{15, nil, 15},
+		{11, nil, 11},
+		{10, ErrCompacted, 0},
+		{0, ErrCompacted, 0},
+		{22, ErrNotFound, 0},
+	}
+	for _, tt := range tests {
+		s, err := ipam.SnapshotAt(tt.rev)
+		if errors.Cause(err) != tt.err {
+			t.Errorf("SnapshotAt(%d) returned %v, want %v", tt.rev, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if n := len(s.Entries()); n != tt.entries {
+			t.Errorf("SnapshotAt(%d) holds %d entries, want %d", tt.rev, n, tt.entries)
+		}
+	}
+
+	s, err := ipam.SnapshotAt(11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ipam.Snapshot()
+	for i := 11; i <= 20; i++ {
+		delete(want.entries, fmt.Sprintf("10.0.0.%d", i))
+	}
+	if !reflect.DeepEqual(keysOf(s), keysOf(want)) {
+		t.Errorf("SnapshotAt(11) = %v, want %v", keysOf(s), keysOf(want))
+	}
+}
+
+func keysOf(s *Snapshot[string]) map[string]struct{} {
+	keys := map[string]struct{}{}
+	for k := range s.entries {
+		keys[k] = struct{}{}
+	}
+	return keys
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"container/heap"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"inet.af/netaddr"
+)
+
+// SummarizeOptions select the prefixes of the tree that are summarized and
+// how they are aggregated
+type SummarizeOptions[T any] struct {
+	// Parent limits the summary to the prefixes more specific than the
+	// parent prefix, all prefixes of the tree when not set
+	Parent string
+	// Match selects the prefixes that are summarized, all prefixes when
+	// not set
+	Match func(Entry[T]) bool
+	// MaxExtra is the amount of addresses an aggregate may cover that are
+	// not part of the selected prefixes, the summary is exact when it is 0
+	MaxExtra uint64
+}
+
+// Summarize returns the minimal set of aggregates that cover the selected
+// prefixes of the tree sorted by address. An exact summary covers the
+// addresses of the selected prefixes and nothing else, a covering summary
+// merges aggregates as long as every aggregate covers at most MaxExtra
+// addresses that are not selected
+func (ipam *IpTree[T]) Summarize(o SummarizeOptions[T]) ([]netaddr.IPPrefix, error) {
+	var parent netaddr.IPPrefix
+	if o.Parent != "" {
+		p, _, err := Canonicalize(o.Parent)
+		if err != nil {
+			return nil, err
+		}
+		if parent, err = netaddr.ParseIPPrefix(p); err != nil {
+			return nil, errors.Wrapf(ErrInvalid, "cannot parse prefix %s: %v", p, err)
+		}
+	}
+
+	var b netaddr.IPSetBuilder
+	add := func(p netaddr.IPPrefix, d *Data[T]) {
+		for k, v := range d.GetValue() {
+			if !strings.Contains(k, "/") {
+				continue
+			}
+			rev, _ := ipam.EntryRevision(k)
+			if o.Match == nil || o.Match(Entry[T]{Key: k, Value: v, Revision: rev}) {
+				b.AddPrefix(p)
+			}
+		}
+	}
+	if o.Parent != "" {
+		// only the subtree of the parent is walked
+		ipam.GetTree(familyOfIP(parent.IP())).WalkPrefix(parent, func(p netaddr.IPPrefix, v interface{}) bool {
+			if d, ok := ipam.getData(v); ok && p != parent {
+				add(p, d)
+			}
+			return true
+		})
+	} else {
+		ipam.Walk(func(p netaddr.IPPrefix, d *Data[T]) bool {
+			add(p, d)
+			return true
+		})
+	}
+	s, err := b.IPSet()
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting prefix set")
+	}
+	exact := s.Prefixes()
+	if o.MaxExtra == 0 {
+		return exact, nil
+	}
+	return coverPrefixes(exact, new(big.Int).SetUint64(o.MaxExtra)), nil
+}
+
+// coverNode is an aggregate in the ordered list of aggregates of
+// coverPrefixes
+type coverNode struct {
+	p          netaddr.IPPrefix
+	prev, next *coverNode
+	merged     bool
+}
+
+// coverMerge is the merge of two neighbouring aggregates in their covering
+// prefix c, which holds extra addresses that are not selected
+type coverMerge struct {
+	left, right *coverNode
+	c           netaddr.IPPrefix
+	extra       *big.Int
+}
+
+// coverMerges is a heap of the merges that adds the least extra addresses
+// first, merges of the same cost are done in address order
+type coverMerges []*coverMerge
+
+func (h coverMerges) Len() int { return len(h) }
+func (h coverMerges) Less(i, j int) bool {
+	if c := h[i].extra.Cmp(h[j].extra); c != 0 {
+		return c < 0
+	}
+	return h[i].left.p.IP().Less(h[j].left.p.IP())
+}
+func (h coverMerges) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *coverMerges) Push(x interface{}) { *h = append(*h, x.(*coverMerge)) }
+func (h *coverMerges) Pop() interface{} {
+	old := *h
+	m := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return m
+}
+
+// coverPrefixes merges the sorted and disjoint exact prefixes in covering
+// prefixes, the merge that adds the least extra addresses is done first
+// until every further merge adds more than maxExtra addresses to an
+// aggregate. Only the merges with the neighbours of a merged aggregate are
+// evaluated again, the selected addresses of a covering prefix are counted
+// with a binary search in the running totals of the exact prefixes
+func coverPrefixes(exact []netaddr.IPPrefix, maxExtra *big.Int) []netaddr.IPPrefix {
+	totals := make([]*big.Int, len(exact)+1)
+	totals[0] = big.NewInt(0)
+	for i, p := range exact {
+		totals[i+1] = new(big.Int).Add(totals[i], PrefixAddressCount(p))
+	}
+	// extra returns the addresses of prefix c that are not in exact, the
+	// exact prefixes that overlap c are inside it and next to each other
+	extra := func(c netaddr.IPPrefix) *big.Int {
+		r := c.Range()
+		lo := sort.Search(len(exact), func(i int) bool {
+			return !exact[i].Range().To().Less(r.From())
+		})
+		hi := sort.Search(len(exact), func(i int) bool {
+			return r.To().Less(exact[i].IP())
+		})
+		n := PrefixAddressCount(c)
+		n.Sub(n, totals[hi])
+		return n.Add(n, totals[lo])
+	}
+
+	h := &coverMerges{}
+	evaluate := func(a, b *coverNode) {
+		if a == nil || b == nil || a.p.IP().Is4() != b.p.IP().Is4() {
+			return
+		}
+		c, err := CoveringPrefix(netaddr.IPRangeFrom(a.p.Range().From(), b.p.Range().To()))
+		if err != nil {
+			return
+		}
+		if n := extra(c); n.Cmp(maxExtra) <= 0 {
+			heap.Push(h, &coverMerge{left: a, right: b, c: c, extra: n})
+		}
+	}
+
+	var head, tail *coverNode
+	for _, p := range exact {
+		n := &coverNode{p: p, prev: tail}
+		if tail == nil {
+			head = n
+		} else {
+			tail.next = n
+			evaluate(tail, n)
+		}
+		tail = n
+	}
+	for h.Len() > 0 {
+		m := heap.Pop(h).(*coverMerge)
+		if m.left.merged || m.right.merged {
+			continue
+		}
+		// the covering prefix can also swallow aggregates next to the pair
+		prev, next := m.left, m.right
+		for prev != nil && m.c.Contains(prev.p.IP()) {
+			prev.merged = true
+			prev = prev.prev
+		}
+		for next != nil && m.c.Contains(next.p.IP()) {
+			next.merged = true
+			next = next.next
+		}
+		n := &coverNode{p: m.c, prev: prev, next: next}
+		if prev == nil {
+			head = n
+		} else {
+			prev.next = n
+		}
+		if next != nil {
+			next.prev = n
+		}
+		evaluate(prev, n)
+		evaluate(n, next)
+	}
+
+	aggregates := []netaddr.IPPrefix{}
+	for n := head; n != nil; n = n.next {
+		aggregates = append(aggregates, n.p)
+	}
+	return aggregates
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"math/big"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"inet.af/netaddr"
+)
+
+func TestSummarize(t *testing.T) {
+	ipam := New[string]()
+	for p, v := range map[string]string{
+		"10.0.0.0/16":       "parent",
+		"10.0.0.0/24":       "a",
+		"10.0.1.0/24":       "a",
+		"10.0.2.0/24":       "b",
+		"10.0.8.0/24":       "a",
+		"10.1.0.0/24":       "a",
+		"2001:db8::/64":     "a",
+		"2001:db8:0:1::/64": "a",
+	} {
+		if err := ipam.AddPrefix(p, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	matchA := func(e Entry[string]) bool { return e.Value == "a" }
+	tests := []struct {
+		name string
+		o    SummarizeOptions[string]
+		want []string
+	}{
+		{
+			name: "all",
+			o:    SummarizeOptions[string]{},
+			want: []string{"10.0.0.0/16", "10.1.0.0/24", "2001:db8::/63"},
+		},
+		{
+			name: "match",
+			o:    SummarizeOptions[string]{Match: matchA},
+			want: []string{"10.0.0.0/23", "10.0.8.0/24", "10.1.0.0/24", "2001:db8::/63"},
+		},
+		{
+			name: "parent",
+			o:    SummarizeOptions[string]{Parent: "10.0.0.0/16"},
+			want: []string{"10.0.0.0/23", "10.0.2.0/24", "10.0.8.0/24"},
+		},
+		{
+			name: "extra below the cost of a merge",
+			o:    SummarizeOptions[string]{Parent: "10.0.0.0/16", MaxExtra: 255},
+			want: []string{"10.0.0.0/23", "10.0.2.0/24", "10.0.8.0/24"},
+		},
+		{
+			name: "extra of one merge",
+			o:    SummarizeOptions[string]{Parent: "10.0.0.0/16", MaxExtra: 256},
+			want: []string{"10.0.0.0/22", "10.0.8.0/24"},
+		},
+		{
+			name: "extra of all merges",
+			o:    SummarizeOptions[string]{Parent: "10.0.0.0/16", Match: matchA, MaxExtra: 4096},
+			want: []string{"10.0.0.0/20"},
+		},
+		{
+			name: "families are not merged",
+			o:    SummarizeOptions[string]{Match: matchA, MaxExtra: 1 << 62},
+			want: []string{"10.0.0.0/15", "2001:db8::/63"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefixes, err := ipam.Summarize(tt.o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, p := range prefixes {
+				got = append(got, p.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("summary %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// naiveCoverPrefixes evaluates every pair of neighbouring aggregates in
+// every round, it is the reference of coverPrefixes
+func naiveCoverPrefixes(exact []netaddr.IPPrefix, maxExtra *big.Int) []netaddr.IPPrefix {
+	extra := func(c netaddr.IPPrefix) *big.Int {
+		n := PrefixAddressCount(c)
+		for _, p := range exact {
+			if c.Contains(p.IP()) {
+				n.Sub(n, PrefixAddressCount(p))
+			}
+		}
+		return n
+	}
+	aggregates := append([]netaddr.IPPrefix{}, exact...)
+	for {
+		best := -1
+		var bestPrefix netaddr.IPPrefix
+		var bestExtra *big.Int
+		for i := 0; i+1 < len(aggregates); i++ {
+			a, b := aggregates[i], aggregates[i+1]
+			if a.IP().Is4() != b.IP().Is4() {
+				continue
+			}
+			c, err := CoveringPrefix(netaddr.IPRangeFrom(a.Range().From(), b.Range().To()))
+			if err != nil {
+				continue
+			}
+			if n := extra(c); n.Cmp(maxExtra) <= 0 && (best < 0 || n.Cmp(bestExtra) < 0) {
+				best, bestPrefix, bestExtra = i, c, n
+			}
+		}
+		if best < 0 {
+			return aggregates
+		}
+		merged := []netaddr.IPPrefix{}
+		for i, p := range aggregates {
+			if i == best {
+				merged = append(merged, bestPrefix)
+			}
+			if !bestPrefix.Contains(p.IP()) {
+				merged = append(merged, p)
+			}
+		}
+		aggregates = merged
+	}
+}
+
+func TestCoverPrefixes(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		var b netaddr.IPSetBuilder
+		for j := 0; j < 1+r.Intn(30); j++ {
+			b.AddPrefix(randomPrefix(r, Families[r.Intn(len(Families))]))
+		}
+		s, err := b.IPSet()
+		if err != nil {
+			t.Fatal(err)
+		}
+		exact := s.Prefixes()
+		maxExtra := big.NewInt(int64(r.Intn(4096)))
+		got := coverPrefixes(exact, maxExtra)
+		if want := naiveCoverPrefixes(exact, maxExtra); !reflect.DeepEqual(got, want) {
+			t.Fatalf("cover of %v with %s extra addresses is %v, want %v", exact, maxExtra, got, want)
+		}
+	}
+}
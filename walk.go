@@ -0,0 +1,168 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"inet.af/netaddr"
+)
+
+// ErrNotFound is returned when a prefix, range or ip address does not exist
+var ErrNotFound = errors.New("not found")
+
+// Entry is a prefix, range or ip address stored in the ipam tree together
+// with its typed value
+type Entry[T any] struct {
+	Key   string
+	Value T
+	// Revision is the revision of the tree at the last change of the entry
+	Revision uint64
+}
+
+// IsRange returns true if the entry is an ip range
+func (e *Entry[T]) IsRange() bool {
+	return strings.Contains(e.Key, "-")
+}
+
+// getValue returns the value stored for key in the tree node of the
+// canonical prefix p
+func (ipam *IpTree[T]) getValue(key string, p netaddr.IPPrefix) (T, bool) {
+	var value T
+	v, ok := ipam.GetTree(familyOfIP(p.IP())).Get(p)
+	if !ok {
+		return value, false
+	}
+	d, ok := ipam.getData(v)
+	if !ok {
+		return value, false
+	}
+	value, ok = d.GetValue()[key]
+	return value, ok
+}
+
+// GetRange returns the value stored for the range ra
+func (ipam *IpTree[T]) GetRange(ra string) (T, bool) {
+	r, err := ParseRange(ra)
+	if err != nil {
+		var value T
+		return value, false
+	}
+	return ipam.GetIPRange(r)
+}
+
+// GetIPRange returns the value stored for the range r
+func (ipam *IpTree[T]) GetIPRange(r netaddr.IPRange) (T, bool) {
+	var value T
+	r, err := canonicalRange(r)
+	if err != nil {
+		return value, false
+	}
+	n, ok := ipam.rangeIndex(familyOfIP(r.From())).get(r)
+	if !ok {
+		return value, false
+	}
+	return n.value, true
+}
+
+// Walk calls fn for every node in the trees of all address families with
+// its typed data, ipv4 is walked before ipv6. The ranges are not held by the
+// nodes. The walk stops when fn returns false
+func (ipam *IpTree[T]) Walk(fn func(netaddr.IPPrefix, *Data[T]) bool) {
+	for _, f := range Families {
+		if !ipam.WalkFamily(f, fn) {
+			return
+		}
+	}
+}
+
+// WalkFamily calls fn for every node in the tree of address family f, it
+// returns false when the walk was stopped by fn
+func (ipam *IpTree[T]) WalkFamily(f Family, fn func(netaddr.IPPrefix, *Data[T]) bool) bool {
+	cont := true
+	ipam.GetTree(f).Walk(func(pfx netaddr.IPPrefix, v interface{}) bool {
+		d, ok := ipam.getData(v)
+		if !ok {
+			return true
+		}
+		cont = fn(pfx, d)
+		return cont
+	})
+	return cont
+}
+
+// Export returns all prefixes, ranges and ip addresses in the tree with
+// their typed values, the ranges of an address family follow its prefixes
+// and ip addresses
+func (ipam *IpTree[T]) Export() []Entry[T] {
+	entries := []Entry[T]{}
+	for _, f := range Families {
+		entries = append(entries, ipam.ExportFamily(f)...)
+	}
+	return entries
+}
+
+// ExportFamily returns the entries of address family f
+func (ipam *IpTree[T]) ExportFamily(f Family) []Entry[T] {
+	entries := []Entry[T]{}
+	ipam.WalkFamily(f, func(_ netaddr.IPPrefix, d *Data[T]) bool {
+		for k, v := range d.GetValue() {
+			rev, _ := ipam.EntryRevision(k)
+			entries = append(entries, Entry[T]{Key: k, Value: v, Revision: rev})
+		}
+		return true
+	})
+	ipam.rangeIndex(f).walk(func(n *rangeNode[T]) bool {
+		rev, _ := ipam.EntryRevision(n.key)
+		entries = append(entries, Entry[T]{Key: n.key, Value: n.value, Revision: rev})
+		return true
+	})
+	return entries
+}
+
+// Stats are the counters of the entries of an address family
+type Stats struct {
+	Nodes     int
+	Prefixes  int
+	Ranges    int
+	Addresses int
+}
+
+// Stats returns the counters of the entries of address family f
+func (ipam *IpTree[T]) Stats(f Family) Stats {
+	stats := Stats{
+		Nodes: ipam.GetTree(f).Size(),
+	}
+	for _, e := range ipam.ExportFamily(f) {
+		switch {
+		case e.IsRange():
+			stats.Ranges++
+		case strings.Contains(e.Key, "/"):
+			stats.Prefixes++
+		default:
+			stats.Addresses++
+		}
+	}
+	return stats
+}
+
+// LongestMatch returns the most specific node of the tree that holds the
+// ip address together with its typed data
+func (ipam *IpTree[T]) LongestMatch(ip string) (netaddr.IPPrefix, *Data[T], bool) {
+	ip, t, err := ipam.tree(ip)
+	if err != nil {
+		return netaddr.IPPrefix{}, nil, false
+	}
+	addr, err := netaddr.ParseIP(ip)
+	if err != nil {
+		return netaddr.IPPrefix{}, nil, false
+	}
+	pfx, v, ok := t.Match(addr)
+	if !ok {
+		return netaddr.IPPrefix{}, nil, false
+	}
+	d, ok := ipam.getData(v)
+	if !ok {
+		return netaddr.IPPrefix{}, nil, false
+	}
+	return pfx, d, true
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"inet.af/netaddr"
+)
+
+// ErrCompacted is returned when a watch starts from a revision that is no
+// longer part of the event history, the consumer should resync from Export
+var ErrCompacted = errors.New("revision compacted")
+
+// defaultEventHistory is the amount of events kept to resume a watch and
+// to take a snapshot of an earlier revision
+const defaultEventHistory = 1000
+
+// watchBuffer is the amount of live events buffered for a watcher, a
+// watcher that falls behind is closed
+const watchBuffer = 256
+
+// EventType is the kind of change of an entry in the tree
+type EventType int
+
+const (
+	EventAdded EventType = iota
+	EventDeleted
+	EventUpdated
+	EventAllocated
+	EventReleased
+	// EventExpired is a release of an ip address whose lease expired
+	EventExpired
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventAdded:
+		return "added"
+	case EventDeleted:
+		return "deleted"
+	case EventUpdated:
+		return "updated"
+	case EventAllocated:
+		return "allocated"
+	case EventReleased:
+		return "released"
+	case EventExpired:
+		return "expired"
+	}
+	return "unknown"
+}
+
+func (t EventType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *EventType) UnmarshalText(b []byte) error {
+	for _, et := range []EventType{EventAdded, EventDeleted, EventUpdated, EventAllocated, EventReleased, EventExpired} {
+		if et.String() == string(b) {
+			*t = et
+			return nil
+		}
+	}
+	return errors.Errorf("unknown event type %s", b)
+}
+
+// Event is a change of a prefix, range or ip address in the tree, the
+// revision of the tree is incremented for every event
+type Event[T any] struct {
+	Type     EventType
+	Key      string
+	OldValue T
+	NewValue T
+	Revision uint64
+	// Time is the time of the change
+	Time time.Time
+}
+
+// WatchFilter selects the events that are sent to a watcher
+type WatchFilter struct {
+	// Types are the event types to watch, all types when empty
+	Types []EventType
+	// Prefix only selects events of entries that overlap with this prefix
+	Prefix string
+	// FromRevision replays the events after this revision before the live
+	// events are sent, 0 only sends live events
+	FromRevision uint64
+}
+
+type watcher[T any] struct {
+	types  map[EventType]struct{}
+	prefix *netaddr.IPPrefix
+	ch     chan Event[T]
+}
+
+// eventLog keeps the revision, the event history and the watchers of a tree
+type eventLog[T any] struct {
+	m        sync.Mutex
+	revision uint64
+	size     int
+	history  []Event[T]
+	watchers map[*watcher[T]]struct{}
+	// entries holds the revision of the last change of every entry
+	entries map[string]uint64
+	// audit records the changes with their actor when it is set
+	audit AuditSink[T]
+	// commit is held across the revision check and the change of the
+	// AtRevision methods, m is taken by the change itself
+	commit sync.Mutex
+}
+
+func newEventLog[T any](size int) *eventLog[T] {
+	if size <= 0 {
+		size = defaultEventHistory
+	}
+	return &eventLog[T]{
+		size:     size,
+		history:  []Event[T]{},
+		watchers: map[*watcher[T]]struct{}{},
+		entries:  map[string]uint64{},
+	}
+}
+
+// WithEventHistory sets the amount of events kept to resume a watch, it
+// also limits how far back SnapshotAt can go
+func WithEventHistory(n int) Option {
+	return func(o *options) {
+		o.eventHistory = n
+	}
+}
+
+// oldest returns the oldest revision that can be restored from the history,
+// it is called with the lock held
+func (l *eventLog[T]) oldest() uint64 {
+	if len(l.history) == 0 {
+		return l.revision
+	}
+	return l.history[0].Revision - 1
+}
+
+// Revision returns the current revision of the tree
+func (ipam *IpTree[T]) Revision() uint64 {
+	if ipam.events == nil {
+		return 0
+	}
+	ipam.events.m.Lock()
+	defer ipam.events.m.Unlock()
+	return ipam.events.revision
+}
+
+// Watch returns a channel with the changes of the tree that match the
+// filter. The channel is closed when the context is done or when the
+// watcher falls behind, a consumer resumes by watching from the revision
+// of the last received event
+func (ipam *IpTree[T]) Watch(ctx context.Context, f WatchFilter) (<-chan Event[T], error) {
+	if ipam.events == nil {
+		return nil, errors.New("tree does not support watch")
+	}
+	w := &watcher[T]{
+		types: map[EventType]struct{}{},
+	}
+	for _, t := range f.Types {
+		w.types[t] = struct{}{}
+	}
+	if f.Prefix != "" {
+		p, _, err := Canonicalize(f.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		pfx, err := netaddr.ParseIPPrefix(p)
+		if err != nil {
+			return nil, errors.Wrapf(ErrInvalid, "cannot parse prefix %s: %v", p, err)
+		}
+		w.prefix = &pfx
+	}
+
+	l := ipam.events
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	replay := []Event[T]{}
+	if f.FromRevision > 0 && f.FromRevision < l.revision {
+		if len(l.history) == 0 || l.history[0].Revision > f.FromRevision+1 {
+			return nil, errors.Wrapf(ErrCompacted, "revision %d", f.FromRevision)
+		}
+		for _, e := range l.history {
+			if e.Revision > f.FromRevision && w.match(e) {
+				replay = append(replay, e)
+			}
+		}
+	}
+	w.ch = make(chan Event[T], len(replay)+watchBuffer)
+	for _, e := range replay {
+		w.ch <- e
+	}
+	l.watchers[w] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+		l.m.Lock()
+		defer l.m.Unlock()
+		if _, ok := l.watchers[w]; ok {
+			delete(l.watchers, w)
+			close(w.ch)
+		}
+	}()
+	return w.ch, nil
+}
+
+func (w *watcher[T]) match(e Event[T]) bool {
+	if len(w.types) > 0 {
+		if _, ok := w.types[e.Type]; !ok {
+			return false
+		}
+	}
+	if w.prefix != nil {
+		r, err := keyRange(e.Key)
+		if err != nil || !r.Overlaps(w.prefix.Range()) {
+			return false
+		}
+	}
+	return true
+}
+
+// emit records a change of the entry with key and sends it to the watchers.
+// When the change cannot be audited it is reverted and the error is
+// returned, the change then never happened for the tree and its watchers
+func (ipam *IpTree[T]) emit(t EventType, k string, oldValue, newValue T) error {
+	if ipam.events == nil {
+		return nil
+	}
+	l := ipam.events
+	l.m.Lock()
+	defer l.m.Unlock()
+	e := Event[T]{
+		Type:     t,
+		Key:      k,
+		OldValue: oldValue,
+		NewValue: newValue,
+		Revision: l.revision + 1,
+		Time:     ipam.now().UTC(),
+	}
+	if err := ipam.audit(e); err != nil {
+		if rerr := ipam.revert(t, k, oldValue); rerr != nil {
+			return errors.Wrapf(rerr, "cannot revert %s after %v", k, err)
+		}
+		return err
+	}
+	l.revision++
+	switch t {
+	case EventDeleted, EventReleased, EventExpired:
+		delete(l.entries, k)
+	default:
+		l.entries[k] = l.revision
+	}
+	l.history = append(l.history, e)
+	if len(l.history) > l.size {
+		l.history = l.history[len(l.history)-l.size:]
+	}
+	for w := range l.watchers {
+		if !w.match(e) {
+			continue
+		}
+		select {
+		case w.ch <- e:
+		default:
+			// the watcher fell behind, it has to resume from its last revision
+			delete(l.watchers, w)
+			close(w.ch)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// receive returns the events that are buffered in the channel and whether
+// the channel is still open
+func receive[T any](ch <-chan Event[T]) ([]Event[T], bool) {
+	events := []Event[T]{}
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return events, false
+			}
+			events = append(events, e)
+		default:
+			return events, true
+		}
+	}
+}
+
+// eventKeys returns the type and key of the events
+func eventKeys[T any](events []Event[T]) []string {
+	keys := []string{}
+	for _, e := range events {
+		keys = append(keys, e.Type.String()+" "+e.Key)
+	}
+	return keys
+}
+
+// watchChanges applies a change of every event type to the tree
+func watchChanges(t *testing.T, ipam *IpTree[string], clock *ManualClock) {
+	t.Helper()
+	steps := []func() error{
+		func() error { return ipam.AddPrefix("10.0.0.0/24", "net") },
+		func() error { return ipam.AddPrefix("10.0.0.0/24", "net2") },
+		func() error { return ipam.AddRange("10.0.0.10-10.0.0.20", "pool") },
+		func() error { return ipam.ClaimIP("10.0.0.5", "host") },
+		func() error { return ipam.ReleaseIP("10.0.0.5") },
+		func() error { return ipam.ClaimIPWithLease("10.0.0.6", "host", "owner", time.Minute) },
+		func() error {
+			clock.Advance(time.Hour)
+			_, err := ipam.ReapExpired()
+			return err
+		},
+		func() error { return ipam.DeleteRange("10.0.0.10-10.0.0.20") },
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+	}
+}
+
+func TestWatchEventTypes(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	ipam := New[string](WithClock(clock))
+	ch, err := ipam.Watch(context.Background(), WatchFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	watchChanges(t, ipam, clock)
+	events, open := receive(ch)
+	if !open {
+		t.Fatal("watch is closed")
+	}
+	want := []string{
+		"added 10.0.0.0/24",
+		"updated 10.0.0.0/24",
+		"added 10.0.0.10-10.0.0.20",
+		"allocated 10.0.0.5",
+		"released 10.0.0.5",
+		"allocated 10.0.0.6",
+		"expired 10.0.0.6",
+		"deleted 10.0.0.10-10.0.0.20",
+	}
+	if got := eventKeys(events); !reflect.DeepEqual(got, want) {
+		t.Errorf("events %v, want %v", got, want)
+	}
+	for i, e := range events {
+		if e.Revision != uint64(i+1) {
+			t.Errorf("event %d has revision %d", i, e.Revision)
+		}
+	}
+	if events[1].OldValue != "net" || events[1].NewValue != "net2" {
+		t.Errorf("update has values %q -> %q", events[1].OldValue, events[1].NewValue)
+	}
+}
+
+func TestWatchFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter WatchFilter
+		want   []string
+	}{
+		{
+			name:   "types",
+			filter: WatchFilter{Types: []EventType{EventAllocated, EventExpired}},
+			want:   []string{"allocated 10.0.0.5", "allocated 10.0.0.6", "expired 10.0.0.6"},
+		},
+		{
+			name:   "prefix",
+			filter: WatchFilter{Prefix: "10.0.0.16/28"},
+			want:   []string{"added 10.0.0.0/24", "updated 10.0.0.0/24", "added 10.0.0.10-10.0.0.20", "deleted 10.0.0.10-10.0.0.20"},
+		},
+		{
+			name:   "types and prefix",
+			filter: WatchFilter{Types: []EventType{EventReleased}, Prefix: "10.0.0.4/30"},
+			want:   []string{"released 10.0.0.5"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			ipam := New[string](WithClock(clock))
+			ch, err := ipam.Watch(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			watchChanges(t, ipam, clock)
+			events, _ := receive(ch)
+			if got := eventKeys(events); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if _, err := New[string]().Watch(context.Background(), WatchFilter{Prefix: "10.0.0.1-10.0.0.2"}); errors.Cause(err) != ErrInvalid {
+		t.Errorf("range filter returned %v, want %v", err, ErrInvalid)
+	}
+}
+
+func TestWatchFromRevision(t *testing.T) {
+	ipam := New[string](WithEventHistory(4))
+	for i := 0; i < 6; i++ {
+		if err := ipam.AddPrefix(fmt.Sprintf("10.0.%d.0/24", i), "net"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the history holds the revisions 3 to 6, a watch from revision 2
+	// replays them
+	ch, err := ipam.Watch(context.Background(), WatchFilter{FromRevision: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ipam.AddPrefix("10.0.6.0/24", "net"); err != nil {
+		t.Fatal(err)
+	}
+	events, _ := receive(ch)
+	want := []string{"added 10.0.2.0/24", "added 10.0.3.0/24", "added 10.0.4.0/24", "added 10.0.5.0/24", "added 10.0.6.0/24"}
+	if got := eventKeys(events); !reflect.DeepEqual(got, want) {
+		t.Errorf("events %v, want %v", got, want)
+	}
+
+	if _, err := ipam.Watch(context.Background(), WatchFilter{FromRevision: 1}); errors.Cause(err) != ErrCompacted {
+		t.Errorf("watch from a compacted revision returned %v, want %v", err, ErrCompacted)
+	}
+}
+
+func TestWatchClose(t *testing.T) {
+	ipam := New[string]()
+	ctx, cancel := context.WithCancel(context.Background())
+	slow, err := ipam.Watch(ctx, WatchFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a watcher that does not receive is closed when its buffer is full
+	for i := 0; i <= watchBuffer; i++ {
+		if err := ipam.AddPrefix(fmt.Sprintf("10.%d.%d.0/24", i/256, i%256), "net"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events, open := receive(slow)
+	if open || len(events) != watchBuffer {
+		t.Errorf("slow watcher received %d events and is open %t", len(events), open)
+	}
+
+	// a watcher is closed when its context is done
+	w, err := ipam.Watch(ctx, WatchFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	select {
+	case _, ok := <-w:
+		if ok {
+			t.Error("watcher received an event after its context is done")
+		}
+	case <-time.After(time.Second):
+		t.Error("watcher is not closed when its context is done")
+	}
+}